@@ -0,0 +1,390 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// benchmarkJSON returns a document containing n records.
+func benchmarkJSON(n int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`{"version":3,"records":[`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"id":%d,"name":"record \"%d\"","active":%v,"tags":["alpha","beta","gamma"],`+
+			`"address":{"street":"%d Main Street","city":"City %d","zip":null},"scores":[1.5,-2.25e3,%d]}`,
+			i, i, i%2 == 0, i, i%100, i)
+	}
+	b.WriteString(`]}`)
+	return b.Bytes()
+}
+
+// legacyDecoder tracks paths over encoding/json.Decoder.Token in the same way as the Decoder did before it had a
+// tokenizer of its own. It is the baseline for the benchmarks.
+type legacyDecoder struct {
+	json.Decoder
+	path    JsonPath
+	context jsonContext
+}
+
+func (d *legacyDecoder) Token() (json.Token, error) {
+	t, err := d.Decoder.Token()
+	if err != nil {
+		return t, err
+	}
+	switch t := t.(type) {
+	case json.Delim:
+		switch t {
+		case '{', '[':
+			if d.context == arrValue {
+				d.path.incTop()
+			}
+			if t == '{' {
+				d.path = append(d.path, "")
+				d.context = objKey
+			} else {
+				d.path = append(d.path, -1)
+				d.context = arrValue
+			}
+		case '}', ']':
+			d.path = d.path[:len(d.path)-1]
+			d.context = d.path.inferContext()
+		}
+		return t, nil
+	case string:
+		if d.context == objKey {
+			d.path[len(d.path)-1] = t
+			d.context = objValue
+			return KeyString(t), nil
+		}
+	}
+	switch d.context {
+	case objValue:
+		d.context = objKey
+	case arrValue:
+		d.path.incTop()
+	}
+	return t, nil
+}
+
+func (d *legacyDecoder) Path() JsonPath {
+	p := make(JsonPath, len(d.path))
+	copy(p, d.path)
+	return p
+}
+
+// scan matches every token against the actions, as Scan did before it could skip values. Matched values are
+// decoded and counted; the actions themselves are not called since they expect a *Decoder.
+func (d *legacyDecoder) scan(ext *PathActions) (int, error) {
+	count := 0
+	for {
+		_, err := d.Token()
+		if err == io.EOF {
+			return count, nil
+		} else if err != nil {
+			return count, err
+		}
+		if node := legacyMatch(&ext.node, d.Path()); node != nil && node.action != nil && d.context == objValue {
+			var v json.RawMessage
+			if err = d.Decode(&v); err != nil {
+				return count, err
+			}
+			d.context = objKey
+			count++
+		}
+	}
+}
+
+// legacyMatch is the trie match used with legacyDecoder.
+func legacyMatch(node *pathNode, path JsonPath) *pathNode {
+	for _, ps := range path {
+		found := false
+		for _, n := range node.childNodes {
+			if n.matchOn == ps {
+				node = n
+				found = true
+				break
+			} else if _, ok := ps.(int); ok && n.matchOn == AnyIndex {
+				node = n
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	return node
+}
+
+func BenchmarkTokenEncodingJSON(b *testing.B) {
+	j := benchmarkJSON(1000)
+	b.SetBytes(int64(len(j)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d := json.NewDecoder(bytes.NewReader(j))
+		for {
+			if _, err := d.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkTokenLegacy(b *testing.B) {
+	j := benchmarkJSON(1000)
+	b.SetBytes(int64(len(j)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d := &legacyDecoder{Decoder: *json.NewDecoder(bytes.NewReader(j))}
+		for {
+			if _, err := d.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkToken(b *testing.B) {
+	j := benchmarkJSON(1000)
+	b.SetBytes(int64(len(j)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d := NewDecoder(bytes.NewReader(j))
+		for {
+			if _, err := d.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkNext(b *testing.B) {
+	j := benchmarkJSON(1000)
+	b.SetBytes(int64(len(j)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d := NewDecoder(bytes.NewReader(j))
+		for {
+			if _, err := d.next(); err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func benchmarkActions(count *int) *PathActions {
+	actions := &PathActions{}
+	actions.Add(func(d *Decoder) error {
+		*count++
+		var v json.RawMessage
+		return d.Decode(&v)
+	}, "records", AnyIndex, "address", "city")
+	return actions
+}
+
+func BenchmarkScanLegacy(b *testing.B) {
+	j := benchmarkJSON(1000)
+	b.SetBytes(int64(len(j)))
+	b.ReportAllocs()
+	actions := benchmarkActions(nil)
+	for i := 0; i < b.N; i++ {
+		d := &legacyDecoder{Decoder: *json.NewDecoder(bytes.NewReader(j))}
+		count, err := d.scan(actions)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if count != 1000 {
+			b.Fatalf("matched %v values", count)
+		}
+	}
+}
+
+func BenchmarkScan(b *testing.B) {
+	j := benchmarkJSON(1000)
+	b.SetBytes(int64(len(j)))
+	b.ReportAllocs()
+	var count int
+	actions := benchmarkActions(&count)
+	for i := 0; i < b.N; i++ {
+		count = 0
+		d := NewDecoder(bytes.NewReader(j))
+		if _, err := d.Scan(actions); err != nil {
+			b.Fatal(err)
+		}
+		if count != 1000 {
+			b.Fatalf("matched %v values", count)
+		}
+	}
+}
+
+// manyActions returns PathActions with n paths registered under each record, including the fields of the records
+// in benchmarkJSON.
+func manyActions(n int, count *int) *PathActions {
+	return manyRecordActions(n, count, "records", AnyIndex)
+}
+
+// manyRecordActions is equivalent to manyActions, with the paths of the records starting with prefix.
+func manyRecordActions(n int, count *int, prefix ...interface{}) *PathActions {
+	action := func(d *Decoder) error {
+		*count++
+		var raw json.RawMessage
+		return d.Decode(&raw)
+	}
+	path := func(p ...interface{}) []interface{} {
+		return append(append([]interface{}(nil), prefix...), p...)
+	}
+	actions := &PathActions{}
+	for i := 0; i < n; i++ {
+		actions.Add(action, path(fmt.Sprintf("field%d", i))...)
+		actions.Add(action, path("address", fmt.Sprintf("line%d", i))...)
+	}
+	actions.Add(action, path("address", "city")...)
+	actions.Add(action, path("name")...)
+	return actions
+}
+
+func benchmarkPaths() []JsonPath {
+	return []JsonPath{
+		{"records", 12, "address", "city"},
+		{"records", 12, "address", "line4999"},
+		{"records", 12, "name"},
+		{"records", 12, "field2500"},
+		{"records", 12, "missing"},
+		{"version"},
+	}
+}
+
+func BenchmarkMatchLinear(b *testing.B) {
+	actions := manyActions(5000, nil)
+	paths := benchmarkPaths()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, p := range paths {
+			legacyMatch(&actions.node, p)
+		}
+	}
+}
+
+func BenchmarkMatchCompiled(b *testing.B) {
+	m := manyActions(5000, nil).Compile()
+	var stacks []pathStack
+	for _, p := range benchmarkPaths() {
+		var s pathStack
+		s.set(p)
+		stacks = append(stacks, s)
+	}
+	state := matchState{m: m}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, s := range stacks {
+			state.reset(s)
+		}
+	}
+}
+
+func BenchmarkScanManyPathsLegacy(b *testing.B) {
+	j := benchmarkJSON(1000)
+	actions := manyActions(5000, nil)
+	b.SetBytes(int64(len(j)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d := &legacyDecoder{Decoder: *json.NewDecoder(bytes.NewReader(j))}
+		count, err := d.scan(actions)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if count != 2000 {
+			b.Fatalf("matched %v values", count)
+		}
+	}
+}
+
+func BenchmarkScanManyPaths(b *testing.B) {
+	j := benchmarkJSON(1000)
+	var count int
+	m := manyActions(5000, &count).Compile()
+	b.SetBytes(int64(len(j)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		count = 0
+		d := NewDecoder(bytes.NewReader(j))
+		if _, err := d.ScanMatcher(m); err != nil {
+			b.Fatal(err)
+		}
+		if count != 2000 {
+			b.Fatalf("matched %v values", count)
+		}
+	}
+}
+
+// BenchmarkScanElements calls Scan for each element of an array in turn, in the way shown in the README.
+func BenchmarkScanElements(b *testing.B) {
+	j := benchmarkJSON(1000)
+	var count int
+	actions := manyRecordActions(1000, &count)
+	b.SetBytes(int64(len(j)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		count = 0
+		d := NewDecoder(bytes.NewReader(j))
+		if _, err := d.SeekTo("records", 0); err != nil {
+			b.Fatal(err)
+		}
+		for ok := true; ok; {
+			var err error
+			if ok, err = d.Scan(actions); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if count != 2000 {
+			b.Fatalf("matched %v values", count)
+		}
+	}
+}
+
+// benchmarkPatch applies a patch of n operations of the form op to records of benchmarkJSON.
+func benchmarkPatch(b *testing.B, op string, n int) {
+	j := benchmarkJSON(1000)
+	var p Patch
+	for i := 0; i < n; i++ {
+		p = append(p, PatchOperation{Op: op, Path: fmt.Sprintf("/records/%d/name", i), Value: json.RawMessage(`"x"`)})
+	}
+	b.SetBytes(int64(len(j)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := p.Apply(NewDecoder(bytes.NewReader(j)), NewEncoder(ioutil.Discard)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPatchReplace applies many replace operations, which share a single pass over the document.
+func BenchmarkPatchReplace(b *testing.B) {
+	benchmarkPatch(b, "replace", 1000)
+}
+
+// BenchmarkPatchAdd applies many add operations, each of which is a pass over the document of its own.
+func BenchmarkPatchAdd(b *testing.B) {
+	benchmarkPatch(b, "add", 100)
+}
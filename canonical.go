@@ -0,0 +1,222 @@
+package jsonpath
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Canonicalize reads the next value from d and writes it to w in the canonical form of RFC 8785, the JSON
+// Canonicalization Scheme (JCS), so that equal values are always written as the same bytes, for example to be
+// signed or hashed. The canonical form has no whitespace; object members are sorted by their keys, compared as
+// UTF-16 code units; numbers are written as by ECMAScript's Number.prototype.toString; and strings are written with
+// only the escapes that JSON requires.
+//
+// The members of each object are held in memory until the end of the object so that they can be sorted, but the
+// elements of a top-level array are written as they are read. Canonicalize returns an error if an object has a
+// repeated key or a number is too large to be represented as an IEEE 754 double. No newline is written after the
+// value.
+func Canonicalize(d *Decoder, w io.Writer) error {
+
+	bw := bufio.NewWriter(w)
+	kind, err := d.next()
+	if err != nil {
+		return err
+	}
+	if kind != tokenArrayStart {
+		b, err := appendCanonical(nil, d, kind)
+		if err != nil {
+			return err
+		}
+		bw.Write(b)
+		return bw.Flush()
+	}
+
+	// the elements of a top-level array are streamed
+	bw.WriteByte('[')
+	var b []byte
+	for i := 0; ; i++ {
+		if kind, err = d.next(); err != nil {
+			return err
+		}
+		if kind == tokenArrayEnd {
+			break
+		}
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		if b, err = appendCanonical(b[:0], d, kind); err != nil {
+			return err
+		}
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+	}
+	bw.WriteByte(']')
+	return bw.Flush()
+}
+
+// canonicalMember is an object member held while the members of an object are sorted.
+type canonicalMember struct {
+	key   []uint16 // the key as UTF-16 code units
+	value []byte   // the key and value in canonical form
+}
+
+// appendCanonical appends the canonical form of the value that begins with the token of the given kind to b.
+func appendCanonical(b []byte, d *Decoder, kind tokenKind) ([]byte, error) {
+
+	var err error
+	switch kind {
+	case tokenObjectStart:
+		var members []canonicalMember
+		seen := map[string]bool{}
+		for {
+			if kind, err = d.next(); err != nil {
+				return nil, err
+			}
+			if kind == tokenObjectEnd {
+				break
+			}
+			key := string(d.path.top().key)
+			if seen[key] {
+				return nil, fmt.Errorf("jsonpath: cannot canonicalize repeated key %q at %s", key,
+					FormatPath(d.Path()))
+			}
+			seen[key] = true
+
+			m := canonicalMember{key: utf16.Encode([]rune(key))}
+			m.value = append(appendCanonicalString(nil, key), ':')
+			if kind, err = d.next(); err != nil {
+				return nil, err
+			}
+			if m.value, err = appendCanonical(m.value, d, kind); err != nil {
+				return nil, err
+			}
+			members = append(members, m)
+		}
+		sort.Slice(members, func(i, j int) bool { return lessUTF16(members[i].key, members[j].key) })
+		b = append(b, '{')
+		for i, m := range members {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, m.value...)
+		}
+		return append(b, '}'), nil
+
+	case tokenArrayStart:
+		b = append(b, '[')
+		for i := 0; ; i++ {
+			if kind, err = d.next(); err != nil {
+				return nil, err
+			}
+			if kind == tokenArrayEnd {
+				return append(b, ']'), nil
+			}
+			if i > 0 {
+				b = append(b, ',')
+			}
+			if b, err = appendCanonical(b, d, kind); err != nil {
+				return nil, err
+			}
+		}
+
+	case tokenString:
+		return appendCanonicalString(b, d.tok.string()), nil
+	case tokenNumber:
+		f, err := strconv.ParseFloat(string(d.tok.raw), 64)
+		if err != nil || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("jsonpath: cannot canonicalize number %s at %s", d.tok.raw, FormatPath(d.Path()))
+		}
+		return append(b, formatCanonicalNumber(f)...), nil
+	case tokenTrue:
+		return append(b, literalTrue...), nil
+	case tokenFalse:
+		return append(b, literalFalse...), nil
+	case tokenNull:
+		return append(b, literalNull...), nil
+	}
+	return nil, fmt.Errorf("jsonpath: unexpected token at %s", FormatPath(d.Path()))
+}
+
+// lessUTF16 reports whether a sorts before b when compared as sequences of UTF-16 code units.
+func lessUTF16(a, b []uint16) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// appendCanonicalString appends s to b as a JSON string, escaping only quotes, backslashes and control characters.
+// The control characters with short escapes are written with them, and the others as \u00xx with lower case hex.
+func appendCanonicalString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c >= 0x20:
+			b = append(b, c)
+		case c == '\b':
+			b = append(b, '\\', 'b')
+		case c == '\t':
+			b = append(b, '\\', 't')
+		case c == '\n':
+			b = append(b, '\\', 'n')
+		case c == '\f':
+			b = append(b, '\\', 'f')
+		case c == '\r':
+			b = append(b, '\\', 'r')
+		default:
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		}
+	}
+	return append(b, '"')
+}
+
+// formatCanonicalNumber formats f as by ECMAScript's Number.prototype.toString, which writes the shortest decimal
+// that identifies f, in exponential notation if its exponent is below -6 or above 20.
+func formatCanonicalNumber(f float64) string {
+	if f == 0 {
+		// including negative zero
+		return "0"
+	}
+
+	// the shortest digits and the exponent of the first of them, from the form d.ddde±x
+	e := strconv.FormatFloat(f, 'e', -1, 64)
+	sign := ""
+	if e[0] == '-' {
+		sign, e = "-", e[1:]
+	}
+	i := strings.IndexByte(e, 'e')
+	digits := strings.Replace(e[:i], ".", "", 1)
+	exp, _ := strconv.Atoi(e[i+1:])
+
+	// n is the position of the decimal point relative to the start of the digits
+	k, n := len(digits), exp+1
+	switch {
+	case k <= n && n <= 21:
+		return sign + digits + strings.Repeat("0", n-k)
+	case 0 < n && n <= 21:
+		return sign + digits[:n] + "." + digits[n:]
+	case -6 < n && n <= 0:
+		return sign + "0." + strings.Repeat("0", -n) + digits
+	}
+	s := sign + digits[:1]
+	if k > 1 {
+		s += "." + digits[1:]
+	}
+	if n-1 > 0 {
+		return s + "e+" + strconv.Itoa(n-1)
+	}
+	return s + "e-" + strconv.Itoa(1-n)
+}
@@ -0,0 +1,105 @@
+package jsonpath
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func canonicalString(s string) (string, error) {
+	var b bytes.Buffer
+	err := Canonicalize(NewDecoder(strings.NewReader(s)), &b)
+	return b.String(), err
+}
+
+// TestCanonicalizeRFC8785 canonicalizes the examples of RFC 8785 in testdata/jcs. Each NAME.json is compared with
+// NAME.canonical.
+func TestCanonicalizeRFC8785(t *testing.T) {
+
+	inputs, err := filepath.Glob(filepath.Join("testdata", "jcs", "*.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, inputs)
+	for _, name := range inputs {
+		f, err := os.Open(name)
+		require.NoError(t, err)
+		var out bytes.Buffer
+		err = Canonicalize(NewDecoder(f), &out)
+		f.Close()
+		require.NoError(t, err, name)
+
+		expected, err := ioutil.ReadFile(strings.TrimSuffix(name, ".json") + ".canonical")
+		require.NoError(t, err)
+		assert.Equal(t, string(expected), out.String(), name)
+	}
+}
+
+// TestCanonicalNumbers checks the number formats of RFC 8785 Appendix B, given as IEEE 754 bit patterns.
+func TestCanonicalNumbers(t *testing.T) {
+
+	for _, tst := range []struct {
+		bits uint64
+		s    string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	} {
+		assert.Equal(t, tst.s, formatCanonicalNumber(math.Float64frombits(tst.bits)), "%016x", tst.bits)
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+
+	for in, out := range map[string]string{
+		`  "aé <>&" `:        `"aé` + " " + `<>&"`,
+		`"\u001f\b\t\n\f\r"`: `"\u001f\b\t\n\f\r"`,
+		`[ {"b": [1.0, {"d":1,"c":2}], "a": -0} , [] , {} ]`: `[{"a":0,"b":[1,{"c":2,"d":1}]},[],{}]`,
+		`-1.5e2`: `-150`,
+		`1e-7`:   `1e-7`,
+	} {
+		s, err := canonicalString(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, out, s, in)
+	}
+
+	// each call reads one value
+	d := NewDecoder(strings.NewReader(`{"b":1,"a":2} [true]`))
+	var b bytes.Buffer
+	for d.More() {
+		require.NoError(t, Canonicalize(d, &b))
+	}
+	assert.Equal(t, `{"a":2,"b":1}[true]`, b.String())
+
+	for _, in := range []string{`{"a":1,"a":2}`, `1e400`, `[1,`, `{"a":}`} {
+		_, err := canonicalString(in)
+		assert.Error(t, err, in)
+	}
+}
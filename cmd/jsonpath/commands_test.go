@@ -0,0 +1,176 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares out with the golden file testdata/name.golden, or writes the file if -update is set.
+func checkGolden(t *testing.T, name, out string) {
+	golden := filepath.Join("testdata", name+".golden")
+	if *update {
+		require.NoError(t, ioutil.WriteFile(golden, []byte(out), 0644))
+		return
+	}
+	expected, err := ioutil.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), out, name)
+}
+
+func TestCommandsGolden(t *testing.T) {
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"flatten", []string{"flatten", "testdata/orders.ndjson"}},
+		{"flatten-sep", []string{"flatten", "-sep", "/", "testdata/orders.ndjson"}},
+		{"project", []string{"project", "-keep", "$.id", "-keep", "$.lines[*].sku", "testdata/orders.ndjson"}},
+		{"project-preserve", []string{"project", "-keep", "$.items[2].name", "-keep", "$.items[3].sku",
+			"-preserve-indices", "-pretty", "testdata/catalog.json"}},
+		{"redact", []string{"redact", "--path", "$.customer.email", "--path", "$.lines[*].price", "--mode", "placeholder",
+			"testdata/orders.ndjson"}},
+		{"redact-hash", []string{"redact", "-path", "$.customer", "-mode", "hash", "-hash-key", "secret",
+			"testdata/orders.ndjson"}},
+		{"gen", []string{"gen", "-type", "Order", "-package", "feeds", "-field", "$.id", "-field", "$.customer.email",
+			"-field", "$.lines[*].sku", "-field", "$.note", "testdata/orders.ndjson"}},
+	}
+
+	for _, tst := range tests {
+		status, out, errs := runCommand("", tst.args...)
+		if assert.Equal(t, exitMatch, status, "%s: %s", tst.name, errs) {
+			checkGolden(t, tst.name, out)
+		}
+	}
+}
+
+func TestRedactCounts(t *testing.T) {
+
+	status, out, errs := runCommand(`{"a":[1,2,3],"b":"x"}`, "redact", "-path", "$.a[*]", "-path", "b", "-mask", "*", "-counts")
+	assert.Equal(t, exitMatch, status)
+	assert.Equal(t, `{"a":["*","*","*"],"b":"*"}`+"\n", out)
+	assert.Equal(t, "$.a[*]\t3\nb\t1\n", errs)
+}
+
+func TestSplitGolden(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "jsonpath")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	status, out, errs := runCommand("", "split", "-array", "$.items", "-chunk", "2", "-dir", dir, "testdata/catalog.json")
+	require.Equal(t, exitMatch, status, errs)
+	assert.Equal(t, strings.Join([]string{
+		filepath.Join(dir, "part-0001.json"),
+		filepath.Join(dir, "part-0002.json"),
+		filepath.Join(dir, "part-0003.json"),
+	}, "\n")+"\n", out)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+		b, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		require.NoError(t, err)
+		checkGolden(t, "split-"+strings.TrimSuffix(f.Name(), ".json"), string(b))
+	}
+	assert.True(t, sort.StringsAreSorted(names))
+	assert.Len(t, names, 3)
+}
+
+func TestSplitRoot(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "jsonpath")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	status, _, errs := runCommand(`[1, {"a": 2}, 3]`, "split", "-array", "$", "-chunk", "5", "-dir", dir, "-prefix", "x")
+	require.Equal(t, exitMatch, status, errs)
+	b, err := ioutil.ReadFile(filepath.Join(dir, "x0001.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "[1,{\"a\":2},3]\n", string(b))
+}
+
+func TestCommandErrors(t *testing.T) {
+
+	for _, args := range [][]string{
+		{"flatten", "-x"},
+		{"project"},
+		{"project", "-keep", "$..a"},
+		{"redact", "-path", "$.a", "-mode", "shred"},
+		{"redact"},
+		{"split", "-array", "$.a", "-chunk", "0"},
+		{"split", "-array", "$.a[*]"},
+		{"split", "-array", "$.a", "a.json", "b.json"},
+		{"flatten", "testdata/missing.json"},
+		{"gen", "-field", "$.a"},
+		{"gen", "-type", "T", "-field", "$"},
+		{"gen", "-type", "T", "-field", "$.b"},
+	} {
+		status, _, errs := runCommand(`{"a":[1]}`, args...)
+		assert.Equal(t, exitError, status, "%v", args)
+		assert.NotEmpty(t, errs, "%v", args)
+	}
+
+	status, _, _ := runCommand(`{"a":1}`, "split", "-array", "$.b")
+	assert.Equal(t, exitNoMatch, status)
+	status, _, _ = runCommand(`{"b":1}`, "split", "-array", "$.b")
+	assert.Equal(t, exitNoMatch, status)
+
+	status, _, _ = runCommand(`{"a":[1,}`, "flatten")
+	assert.Equal(t, exitError, status)
+	status, _, _ = runCommand(`{"a":[1,}`, "project", "-keep", "$.a")
+	assert.Equal(t, exitError, status)
+}
+
+func TestGenOutput(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "jsonpath")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "t_gen.go")
+	status, out, errs := runCommand(`{"a":[1.5]}`, "gen", "-type", "T", "-o", name)
+	require.Equal(t, exitMatch, status, errs)
+	assert.Empty(t, out)
+	b, err := ioutil.ReadFile(name)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "package ")
+	assert.Contains(t, string(b), "type T struct {\n\tA []float64 `json:\"a\"`\n}\n")
+}
+
+// TestGenActions compares the actions generated for the sample orders with internal/feeds, where they are compiled
+// and run against the samples by the package's tests.
+func TestGenActions(t *testing.T) {
+
+	status, out, errs := runCommand("", "gen", "-type", "Order", "-package", "feeds", "-actions", "-field", "$.id",
+		"-field", "$.lines[*].price", "-field", "$.lines[0].qty", "testdata/orders.ndjson")
+	require.Equal(t, exitMatch, status, errs)
+
+	name := filepath.Join("internal", "feeds", "order_actions.go")
+	if *update {
+		require.NoError(t, ioutil.WriteFile(name, []byte(out), 0644))
+		return
+	}
+	expected, err := ioutil.ReadFile(name)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), out)
+}
+
+func TestQuerySubcommandName(t *testing.T) {
+
+	status, out, _ := runCommand(`{"flatten":1}`, "$.flatten")
+	assert.Equal(t, exitMatch, status)
+	assert.Equal(t, "1\n", out)
+}
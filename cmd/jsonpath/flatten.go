@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/exponent-io/jsonpath"
+)
+
+// runFlatten prints the leaves of the input as lines of a path, a tab and a JSON value.
+func runFlatten(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	flags := newFlagSet("flatten", "[flags] [FILE...]", stderr)
+	sep := flags.String("sep", "", "join the keys and indices of paths with `SEP` instead of writing path expressions")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	f := &jsonpath.Flattener{}
+	if *sep != "" {
+		f.Format = jsonpath.JoinPath(*sep)
+	}
+	out := bufio.NewWriter(stdout)
+	err := eachFile(flags.Args(), stdin, func(name string, r io.Reader) error {
+		d := jsonpath.NewDecoder(r)
+		d.UseNumber()
+		for d.More() {
+			err := f.Flatten(d, func(l jsonpath.Leaf) error {
+				v, err := json.Marshal(l.Value)
+				if err != nil {
+					return err
+				}
+				out.WriteString(l.Key)
+				out.WriteByte('\t')
+				out.Write(v)
+				return out.WriteByte('\n')
+			})
+			if err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+		if _, err := d.Token(); err != io.EOF {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		return nil
+	}, stderr)
+	if err := out.Flush(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	if err != nil {
+		return exitError
+	}
+	return exitMatch
+}
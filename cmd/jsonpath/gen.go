@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil" // os.WriteFile needs Go 1.16, and go.mod targets Go 1.15
+	"os"
+	"strings"
+
+	"github.com/exponent-io/jsonpath"
+)
+
+// runGen writes Go declarations for the values in sample JSON documents.
+func runGen(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	flags := newFlagSet("gen", "-type NAME [flags] [FILE...]", stderr)
+	typeName := flags.String("type", "", "the `NAME` of the type that describes the samples")
+	pkg := flags.String("package", os.Getenv("GOPACKAGE"), "the `PACKAGE` of the generated file; $GOPACKAGE by default, as set by go generate")
+	output := flags.String("o", "", "write the generated code to `FILE` instead of standard output")
+	actions := flags.Bool("actions", false, "generate a function that returns PathActions for the fields instead of jsonpath tags")
+	var exprs exprList
+	flags.Var(&exprs, "field", "a path `EXPR` to decode into a field of the NAMEFields type; may be repeated")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if *typeName == "" {
+		flags.Usage()
+		return exitError
+	}
+	if *pkg == "" {
+		*pkg = "main"
+	}
+	paths, err := parsePaths(exprs)
+	if err == nil {
+		for i, p := range paths {
+			if len(p) == 0 {
+				err = fmt.Errorf("jsonpath: field %s is the whole document", exprs[i])
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	in := &jsonpath.Inferrer{Examples: -1}
+	err = eachFile(flags.Args(), stdin, func(name string, r io.Reader) error {
+		d := jsonpath.NewDecoder(r)
+		for d.More() {
+			if err := in.Infer(d); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+		if _, err := d.Token(); err != io.EOF {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		return nil
+	}, stderr)
+	if err != nil {
+		return exitError
+	}
+
+	g := &generator{in: in, pkg: *pkg, name: *typeName, actions: *actions}
+	src, err := g.generate(paths)
+	if err == nil {
+		if *output != "" {
+			err = ioutil.WriteFile(*output, src, 0644)
+		} else {
+			_, err = stdout.Write(src)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "jsonpath: %v\n", err)
+		return exitError
+	}
+	return exitMatch
+}
+
+// generator writes the source of a Go file for an Inferrer.
+type generator struct {
+	in      *jsonpath.Inferrer
+	pkg     string
+	name    string
+	actions bool
+
+	b bytes.Buffer
+}
+
+// genField is a field of the NAMEFields type.
+type genField struct {
+	name     string
+	path     jsonpath.JsonPath
+	elem     string // the type of each value at the path
+	appends  bool   // the path contains AnyIndex, so the field is a slice of elem
+	required bool
+}
+
+// generate returns the formatted source of the type that describes the samples, followed by the NAMEFields type with
+// a field for each of the paths and, if actions are generated, the function that fills it.
+func (g *generator) generate(paths []jsonpath.JsonPath) ([]byte, error) {
+
+	fields, err := g.fields(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	g.b.WriteString("// Code generated by jsonpath gen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&g.b, "package %s\n\n", g.pkg)
+	if g.actions && len(fields) > 0 {
+		g.b.WriteString("import \"github.com/exponent-io/jsonpath\"\n\n")
+	}
+
+	typ, err := g.in.GoType(g.name)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&g.b, "// %s describes the sample documents.\n%s\n", g.name, typ)
+	if len(fields) > 0 {
+		g.fieldsType(fields)
+		if g.actions {
+			g.actionsFunc(fields)
+		}
+	}
+	return format.Source(g.b.Bytes())
+}
+
+// fields returns the fields for the paths, which must each have been seen in the samples.
+func (g *generator) fields(paths []jsonpath.JsonPath) ([]genField, error) {
+
+	optional := map[string]bool{}
+	for _, f := range g.in.Fields() {
+		optional[jsonpath.FormatPath(f.Path)] = f.Optional
+	}
+
+	fields := make([]genField, len(paths))
+	used := map[string]bool{}
+	for i, p := range paths {
+		elem, ok := g.in.GoTypeAt(p)
+		if !ok {
+			return nil, fmt.Errorf("no values at %s in the samples", jsonpath.FormatPath(p))
+		}
+		f := genField{path: p, elem: elem, required: true}
+
+		// a value is required if it is not in an array and it and the objects that contain it are always present
+		for j, e := range p {
+			if _, ok := e.(string); !ok {
+				f.appends = f.appends || e == jsonpath.AnyIndex
+				f.required = false
+			} else if optional[jsonpath.FormatPath(p[:j+1])] {
+				f.required = false
+			}
+		}
+
+		base := jsonpath.GoName(p)
+		f.name = base
+		for n := 2; used[f.name]; n++ {
+			f.name = fmt.Sprintf("%s%d", base, n)
+		}
+		used[f.name] = true
+		fields[i] = f
+	}
+	return fields, nil
+}
+
+// fieldsType writes the NAMEFields type.
+func (g *generator) fieldsType(fields []genField) {
+	how := "decoded with jsonpath.Unmarshal"
+	if g.actions {
+		how = fmt.Sprintf("decoded by %sFieldsActions", g.name)
+	}
+	fmt.Fprintf(&g.b, "\n// %sFields holds the values at chosen paths of the sample documents, %s.\n", g.name, how)
+	fmt.Fprintf(&g.b, "type %sFields struct {\n", g.name)
+	for _, f := range fields {
+		fmt.Fprintf(&g.b, "%s %s", f.name, f.typ())
+		if !g.actions {
+			tag := jsonpath.FormatPath(f.path)
+			if f.required {
+				tag += ",required"
+			}
+			fmt.Fprintf(&g.b, " `jsonpath:%q`", tag)
+		}
+		g.b.WriteByte('\n')
+	}
+	g.b.WriteString("}\n")
+}
+
+// actionsFunc writes the NAMEFieldsActions function.
+func (g *generator) actionsFunc(fields []genField) {
+	fmt.Fprintf(&g.b, "\n// %sFieldsActions returns PathActions that decode the values at the chosen paths into v. Use them to\n"+
+		"// scan each document from its start.\n", g.name)
+	fmt.Fprintf(&g.b, "func %sFieldsActions(v *%[1]sFields) *jsonpath.PathActions {\n", g.name)
+	g.b.WriteString("actions := &jsonpath.PathActions{}\n")
+	for _, f := range fields {
+		g.b.WriteString("actions.Add(func(d *jsonpath.Decoder) error {\n")
+		if f.appends {
+			fmt.Fprintf(&g.b, "var e %s\nif err := d.Decode(&e); err != nil {\nreturn err\n}\n", f.elem)
+			fmt.Fprintf(&g.b, "v.%s = append(v.%[1]s, e)\nreturn nil\n", f.name)
+		} else {
+			fmt.Fprintf(&g.b, "return d.Decode(&v.%s)\n", f.name)
+		}
+		elems := make([]string, len(f.path))
+		for i, e := range f.path {
+			switch e := e.(type) {
+			case string:
+				elems[i] = fmt.Sprintf("%q", e)
+			default:
+				if e == jsonpath.AnyIndex {
+					elems[i] = "jsonpath.AnyIndex"
+				} else {
+					elems[i] = fmt.Sprint(e)
+				}
+			}
+		}
+		fmt.Fprintf(&g.b, "}, %s)\n", strings.Join(elems, ", "))
+	}
+	g.b.WriteString("return actions\n}\n")
+}
+
+// typ returns the Go type of the field.
+func (f genField) typ() string {
+	if f.appends {
+		return "[]" + f.elem
+	}
+	return f.elem
+}
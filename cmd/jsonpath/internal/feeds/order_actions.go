@@ -0,0 +1,49 @@
+// Code generated by jsonpath gen; DO NOT EDIT.
+
+package feeds
+
+import "github.com/exponent-io/jsonpath"
+
+// Order describes the sample documents.
+type Order struct {
+	ID       int64 `json:"id"`
+	Customer struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	} `json:"customer"`
+	Lines []struct {
+		SKU   string  `json:"sku"`
+		Qty   int64   `json:"qty"`
+		Price float64 `json:"price"`
+	} `json:"lines"`
+	Tags []string    `json:"tags"`
+	Note interface{} `json:"note,omitempty"`
+}
+
+// OrderFields holds the values at chosen paths of the sample documents, decoded by OrderFieldsActions.
+type OrderFields struct {
+	ID         int64
+	LinesPrice []float64
+	LinesQty   int64
+}
+
+// OrderFieldsActions returns PathActions that decode the values at the chosen paths into v. Use them to
+// scan each document from its start.
+func OrderFieldsActions(v *OrderFields) *jsonpath.PathActions {
+	actions := &jsonpath.PathActions{}
+	actions.Add(func(d *jsonpath.Decoder) error {
+		return d.Decode(&v.ID)
+	}, "id")
+	actions.Add(func(d *jsonpath.Decoder) error {
+		var e float64
+		if err := d.Decode(&e); err != nil {
+			return err
+		}
+		v.LinesPrice = append(v.LinesPrice, e)
+		return nil
+	}, "lines", jsonpath.AnyIndex, "price")
+	actions.Add(func(d *jsonpath.Decoder) error {
+		return d.Decode(&v.LinesQty)
+	}, "lines", 0, "qty")
+	return actions
+}
@@ -0,0 +1,31 @@
+package feeds
+
+import (
+	"os"
+	"testing"
+
+	"github.com/exponent-io/jsonpath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderFieldsActions(t *testing.T) {
+
+	f, err := os.Open("../../testdata/orders.ndjson")
+	require.NoError(t, err)
+	defer f.Close()
+
+	d := jsonpath.NewDecoder(f)
+	var orders []OrderFields
+	for d.More() {
+		var v OrderFields
+		_, err := d.Scan(OrderFieldsActions(&v))
+		require.NoError(t, err)
+		orders = append(orders, v)
+	}
+
+	assert.Equal(t, []OrderFields{
+		{ID: 1, LinesPrice: []float64{1.5, 10}, LinesQty: 2},
+		{ID: 2, LinesPrice: []float64{0.25}, LinesQty: 5},
+	}, orders)
+}
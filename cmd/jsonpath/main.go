@@ -0,0 +1,306 @@
+// Command jsonpath prints the values at paths in streams of JSON documents, reading them a token at a time so that
+// large inputs are never held in memory.
+//
+// Usage:
+//
+//	jsonpath [flags] EXPR [FILE...]
+//	jsonpath [flags] -e EXPR [-e EXPR...] [FILE...]
+//
+// Each EXPR is a path expression such as $.a.b[0].c or $.items[*].id, as accepted by jsonpath.ParsePath. The files
+// are read in turn, or standard input if there are none or a file is named -. Each file may contain any number of
+// JSON values, so newline-delimited JSON is read one document at a time. Every value that matches one of the paths
+// is printed on its own line, in the order the values appear.
+//
+// The flags are:
+//
+//	-e EXPR   a path to match; may be repeated
+//	-p        print the path of each value before it, separated by a tab
+//	-r        print strings without quotes or escapes
+//	-pretty   print objects and arrays indented over several lines
+//
+// The exit status is 0 if any value matched, 1 if no value matched, and 2 if an error occurred.
+//
+// Subcommands transform the input instead of querying it:
+//
+//	jsonpath flatten [-sep SEP] [FILE...]
+//	jsonpath project -keep EXPR [-keep EXPR...] [-preserve-indices] [-pretty] [FILE...]
+//	jsonpath redact -path EXPR [-path EXPR...] [-mode mask|hash|placeholder] [-mask TEXT] [-hash-key KEY]
+//	                [-counts] [-pretty] [FILE...]
+//	jsonpath split -array EXPR [-chunk N] [-dir DIR] [-prefix PREFIX] [FILE]
+//	jsonpath gen -type NAME [-package PKG] [-o FILE] [-field EXPR...] [-actions] [FILE...]
+//
+// flatten prints each scalar value, and each empty object or array, on its own line as its path, a tab and the value.
+// project prints only the values at the kept paths, with the objects and arrays that enclose them. redact prints its
+// input with the values at the given paths replaced. split writes the elements of the array at a path to numbered files
+// of up to N elements each, and prints the name of each file. gen infers a Go type from sample documents and writes it
+// as Go source, along with a NAMEFields type that holds the values at each -field path, tagged for jsonpath.Unmarshal
+// or, with -actions, filled by a generated PathActions; it is meant to be run by go generate, which sets the default
+// package. Run a subcommand with -h for details of its flags. To query a key with the same name as a subcommand, write
+// the path with a leading $ or use -e.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/exponent-io/jsonpath"
+)
+
+// Exit statuses.
+const (
+	exitMatch   = 0
+	exitNoMatch = 1
+	exitError   = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// exprList is a flag.Value that collects the expressions of repeated flags.
+type exprList []string
+
+func (l *exprList) String() string { return strings.Join(*l, " ") }
+
+func (l *exprList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// query holds the options of a run.
+type query struct {
+	paths     []jsonpath.JsonPath
+	showPaths bool
+	raw       bool
+	pretty    bool
+
+	out     *bufio.Writer
+	matched bool
+}
+
+// command runs a subcommand with the arguments that follow its name and returns its exit status.
+type command func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+
+var commands = map[string]command{
+	"flatten": runFlatten,
+	"gen":     runGen,
+	"project": runProject,
+	"redact":  runRedact,
+	"split":   runSplit,
+}
+
+// run runs the command with the given arguments and returns its exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	if len(args) > 0 {
+		if cmd, ok := commands[args[0]]; ok {
+			return cmd(args[1:], stdin, stdout, stderr)
+		}
+	}
+
+	flags := flag.NewFlagSet("jsonpath", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: jsonpath [flags] EXPR [FILE...]\n       jsonpath [flags] -e EXPR [-e EXPR...] [FILE...]\n       jsonpath flatten|gen|project|redact|split [flags] [FILE...]")
+		flags.PrintDefaults()
+	}
+	var exprs exprList
+	q := &query{out: bufio.NewWriter(stdout)}
+	flags.Var(&exprs, "e", "a path `EXPR` to match; may be repeated")
+	flags.BoolVar(&q.showPaths, "p", false, "print the path of each value before it")
+	flags.BoolVar(&q.raw, "r", false, "print strings without quotes or escapes")
+	flags.BoolVar(&q.pretty, "pretty", false, "print objects and arrays indented over several lines")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	files := flags.Args()
+	if len(exprs) == 0 {
+		if len(files) == 0 {
+			flags.Usage()
+			return exitError
+		}
+		exprs, files = exprList{files[0]}, files[1:]
+	}
+	var err error
+	if q.paths, err = parsePaths(exprs); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	err = eachFile(files, stdin, func(name string, r io.Reader) error {
+		if err := q.scan(r); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		return nil
+	}, stderr)
+	if err := q.out.Flush(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	if err != nil {
+		return exitError
+	}
+	if !q.matched {
+		return exitNoMatch
+	}
+	return exitMatch
+}
+
+// parsePaths parses path expressions with jsonpath.ParsePath.
+func parsePaths(exprs []string) ([]jsonpath.JsonPath, error) {
+	paths := make([]jsonpath.JsonPath, len(exprs))
+	for i, expr := range exprs {
+		p, err := jsonpath.ParsePath(expr)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = p
+	}
+	return paths, nil
+}
+
+// eachFile calls fn with each of the named files in turn, or with stdin if there are none or a file is named -.
+// Errors are reported to stderr, and the first is returned after every file has been tried.
+func eachFile(files []string, stdin io.Reader, fn func(name string, r io.Reader) error, stderr io.Writer) error {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	var first error
+	for _, name := range files {
+		var err error
+		if name == "-" {
+			err = fn("<stdin>", stdin)
+		} else {
+			var f *os.File
+			if f, err = os.Open(name); err == nil {
+				err = fn(name, f)
+				f.Close()
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "jsonpath: %v\n", err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// scan prints the values that match the query in each JSON value read from r.
+func (q *query) scan(r io.Reader) error {
+
+	actions := &jsonpath.PathActions{}
+	root := false
+	for _, p := range q.paths {
+		if len(p) == 0 {
+			root = true
+			continue
+		}
+		actions.Add(func(d *jsonpath.Decoder) error {
+			var raw json.RawMessage
+			if err := d.Decode(&raw); err != nil {
+				return err
+			}
+			return q.print(d.Path(), raw)
+		}, p...)
+	}
+
+	d := jsonpath.NewDecoder(r)
+	for d.More() {
+		if !root {
+			if _, err := d.Scan(actions); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// the whole value matches, and the other paths are matched within it
+		var raw json.RawMessage
+		if err := d.Decode(&raw); err != nil {
+			return err
+		}
+		if err := q.print(jsonpath.JsonPath{}, raw); err != nil {
+			return err
+		}
+		if _, err := jsonpath.NewDecoder(bytes.NewReader(raw)).Scan(actions); err != nil {
+			return err
+		}
+	}
+
+	// report a syntax error that ends the input
+	if _, err := d.Token(); err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// print writes a matched value, preceded by its path if paths are shown.
+func (q *query) print(path jsonpath.JsonPath, raw json.RawMessage) error {
+	q.matched = true
+	if q.showPaths {
+		q.out.WriteString(jsonpath.FormatPath(path))
+		q.out.WriteByte('\t')
+	}
+
+	var s string
+	if q.raw && len(raw) > 0 && raw[0] == '"' && json.Unmarshal(raw, &s) == nil {
+		q.out.WriteString(s)
+	} else {
+		var b bytes.Buffer
+		var err error
+		if q.pretty {
+			err = json.Indent(&b, raw, "", "  ")
+		} else {
+			err = json.Compact(&b, raw)
+		}
+		if err != nil {
+			return err
+		}
+		q.out.Write(b.Bytes())
+	}
+	return q.out.WriteByte('\n')
+}
+
+// newFlagSet returns a FlagSet for a subcommand that reports errors and usage to stderr.
+func newFlagSet(name, usage string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet("jsonpath "+name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: jsonpath %s %s\n", name, usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// transform calls fn to copy each of the files, or stdin, to stdout, and returns the exit status.
+func transform(files []string, stdin io.Reader, stdout, stderr io.Writer, pretty bool,
+	fn func(d *jsonpath.Decoder, e *jsonpath.Encoder) error) int {
+
+	out := bufio.NewWriter(stdout)
+	e := jsonpath.NewEncoder(out)
+	if pretty {
+		e.SetIndent("", "  ")
+	}
+	err := eachFile(files, stdin, func(name string, r io.Reader) error {
+		if err := fn(jsonpath.NewDecoder(r), e); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		return nil
+	}, stderr)
+	if err := out.Flush(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	if err != nil {
+		return exitError
+	}
+	return exitMatch
+}
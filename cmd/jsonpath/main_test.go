@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runCommand(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	status := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+const orders = `{"id":1,"lines":[{"sku":"a","qty":2},{"sku":"b","qty":1}],"note":"x\ty"}
+{"id":2,"lines":[],"note":null}
+{"id":3,"lines":[{"sku":"c","qty":5}]}
+`
+
+func TestQuery(t *testing.T) {
+
+	status, out, _ := runCommand(orders, "$.lines[*].sku")
+	assert.Equal(t, exitMatch, status)
+	assert.Equal(t, "\"a\"\n\"b\"\n\"c\"\n", out)
+
+	status, out, _ = runCommand(orders, "-r", "-p", "-e", "$.id", "-e", "$.lines[1].sku", "-e", "note")
+	assert.Equal(t, exitMatch, status)
+	assert.Equal(t, "$.id\t1\n$.lines[1].sku\tb\n$.note\tx\ty\n$.id\t2\n$.note\tnull\n$.id\t3\n", out)
+
+	status, out, _ = runCommand(`{"a": {"b": [1, 2]}}`, "-pretty", "$.a")
+	assert.Equal(t, exitMatch, status)
+	assert.Equal(t, "{\n  \"b\": [\n    1,\n    2\n  ]\n}\n", out)
+
+	status, out, _ = runCommand(`{"a": [1, 2]} [3]`, "-e", "$", "-e", "$.a[0]")
+	assert.Equal(t, exitMatch, status)
+	assert.Equal(t, "{\"a\":[1,2]}\n1\n[3]\n", out)
+}
+
+func TestQueryNoMatch(t *testing.T) {
+
+	status, out, _ := runCommand(orders, "$.missing")
+	assert.Equal(t, exitNoMatch, status)
+	assert.Empty(t, out)
+
+	status, _, _ = runCommand("", "$.a")
+	assert.Equal(t, exitNoMatch, status)
+}
+
+func TestQueryFiles(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "jsonpath")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	a := filepath.Join(dir, "a.json")
+	require.NoError(t, ioutil.WriteFile(a, []byte(`{"v":"file"}`), 0600))
+
+	status, out, _ := runCommand(`{"v":"stdin"}`, "-r", "$.v", a, "-")
+	assert.Equal(t, exitMatch, status)
+	assert.Equal(t, "file\nstdin\n", out)
+
+	status, out, errs := runCommand("", "$.v", filepath.Join(dir, "missing.json"), a)
+	assert.Equal(t, exitError, status)
+	assert.Equal(t, "\"file\"\n", out)
+	assert.Contains(t, errs, "missing.json")
+}
+
+func TestQueryErrors(t *testing.T) {
+
+	status, _, errs := runCommand(`{}`)
+	assert.Equal(t, exitError, status)
+	assert.Contains(t, errs, "usage")
+
+	status, _, errs = runCommand(`{}`, "$..a")
+	assert.Equal(t, exitError, status)
+	assert.Contains(t, errs, "invalid path")
+
+	status, _, _ = runCommand(`{}`, "-x", "$.a")
+	assert.Equal(t, exitError, status)
+
+	status, out, errs := runCommand(`{"a":1} {"a":`, "$.a")
+	assert.Equal(t, exitError, status)
+	assert.Equal(t, "1\n", out)
+	assert.Contains(t, errs, "<stdin>")
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/exponent-io/jsonpath"
+)
+
+// runProject prints the input with only the values at the kept paths.
+func runProject(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	flags := newFlagSet("project", "-keep EXPR [-keep EXPR...] [flags] [FILE...]", stderr)
+	var keep exprList
+	flags.Var(&keep, "keep", "a path `EXPR` to keep; may be repeated")
+	preserve := flags.Bool("preserve-indices", false, "write dropped array elements before kept ones as null")
+	pretty := flags.Bool("pretty", false, "print objects and arrays indented over several lines")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if len(keep) == 0 {
+		flags.Usage()
+		return exitError
+	}
+	paths, err := parsePaths(keep)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	p := &jsonpath.Projection{PreserveIndices: *preserve}
+	for _, path := range paths {
+		p.Add(path...)
+	}
+	return transform(flags.Args(), stdin, stdout, stderr, *pretty, p.Project)
+}
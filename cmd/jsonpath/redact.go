@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/exponent-io/jsonpath"
+)
+
+var redactModes = map[string]jsonpath.RedactMode{
+	"mask":        jsonpath.RedactMask,
+	"hash":        jsonpath.RedactHash,
+	"placeholder": jsonpath.RedactPlaceholder,
+}
+
+// runRedact prints the input with the values at the given paths redacted.
+func runRedact(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	flags := newFlagSet("redact", "-path EXPR [-path EXPR...] [flags] [FILE...]", stderr)
+	var exprs exprList
+	flags.Var(&exprs, "path", "a path `EXPR` to redact; may be repeated")
+	mode := flags.String("mode", "mask", "replace values with a mask, a hash or a placeholder of the same type")
+	r := &jsonpath.Redactor{}
+	flags.StringVar(&r.Mask, "mask", jsonpath.DefaultMask, "the `TEXT` that replaces masked values")
+	key := flags.String("hash-key", "", "hash values with HMAC-SHA256 using `KEY`")
+	counts := flags.Bool("counts", false, "print the number of values redacted at each path to standard error")
+	pretty := flags.Bool("pretty", false, "print objects and arrays indented over several lines")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	m, ok := redactModes[*mode]
+	if !ok || len(exprs) == 0 {
+		flags.Usage()
+		return exitError
+	}
+	paths, err := parsePaths(exprs)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	if *key != "" {
+		r.HashKey = []byte(*key)
+	}
+	for _, path := range paths {
+		r.Add(m, path...)
+	}
+	total := make([]int, len(paths))
+	status := transform(flags.Args(), stdin, stdout, stderr, *pretty, func(d *jsonpath.Decoder, e *jsonpath.Encoder) error {
+		c, err := r.Redact(d, e)
+		for i := range c {
+			total[i] += c[i].Count
+		}
+		return err
+	})
+	if *counts {
+		for i, expr := range exprs {
+			fmt.Fprintf(stderr, "%s\t%d\n", expr, total[i])
+		}
+	}
+	return status
+}
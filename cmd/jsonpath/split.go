@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/exponent-io/jsonpath"
+)
+
+// runSplit writes the elements of an array in the input to numbered files.
+func runSplit(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	flags := newFlagSet("split", "-array EXPR [flags] [FILE]", stderr)
+	expr := flags.String("array", "", "the path `EXPR` of the array to split")
+	chunk := flags.Int("chunk", 1000, "the maximum number of elements in each file")
+	dir := flags.String("dir", ".", "the directory to write the files to")
+	prefix := flags.String("prefix", "part-", "the beginning of the name of each file, which is followed by a number and .json")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if *expr == "" || *chunk < 1 || flags.NArg() > 1 {
+		flags.Usage()
+		return exitError
+	}
+	path, err := jsonpath.ParsePath(*expr)
+	if err == nil {
+		for _, e := range path {
+			if e == jsonpath.AnyIndex {
+				err = fmt.Errorf("jsonpath: the path of the array to split cannot contain [*]")
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	s := &splitter{chunk: *chunk, dir: *dir, prefix: *prefix, stdout: stdout}
+	found := false
+	err = eachFile(flags.Args(), stdin, func(name string, r io.Reader) error {
+		var err error
+		found, err = s.split(jsonpath.NewDecoder(r), path)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		return nil
+	}, stderr)
+	if err != nil {
+		return exitError
+	}
+	if !found {
+		fmt.Fprintf(stderr, "jsonpath: no array at %s\n", *expr)
+		return exitNoMatch
+	}
+	return exitMatch
+}
+
+// splitter writes the elements of an array to files.
+type splitter struct {
+	chunk  int
+	dir    string
+	prefix string
+	stdout io.Writer
+
+	files int // the number of files written
+}
+
+// split writes the elements of the array at path in the input to files, and reports whether the array was found.
+func (s *splitter) split(d *jsonpath.Decoder, path jsonpath.JsonPath) (bool, error) {
+
+	// SeekTo modifies its argument
+	if ok, err := d.SeekTo(append(jsonpath.JsonPath{}, path...)...); !ok || err != nil {
+		return false, err
+	}
+	t, err := d.Token()
+	if err != nil {
+		return false, err
+	}
+	if t != json.Delim('[') {
+		return false, nil
+	}
+
+	for d.More() {
+		if err := s.writeFile(d); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// writeFile writes up to chunk elements of the array from d to the next file.
+func (s *splitter) writeFile(d *jsonpath.Decoder) (err error) {
+
+	s.files++
+	name := filepath.Join(s.dir, fmt.Sprintf("%s%04d.json", s.prefix, s.files))
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	w := bufio.NewWriter(f)
+	e := jsonpath.NewEncoder(w)
+	if err := e.WriteToken(json.Delim('[')); err != nil {
+		return err
+	}
+	for n := 0; n < s.chunk && d.More(); n++ {
+		var v json.RawMessage
+		if err := d.Decode(&v); err != nil {
+			return err
+		}
+		if err := e.WriteValue(v); err != nil {
+			return err
+		}
+	}
+	if err := e.WriteToken(json.Delim(']')); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(s.stdout, name)
+	return err
+}
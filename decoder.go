@@ -1,6 +1,7 @@
 package jsonpath
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 )
@@ -9,16 +10,85 @@ import (
 type KeyString string
 
 // Decoder extends the Go runtime's encoding/json.Decoder to support navigating in a stream of JSON tokens.
+//
+// Decoder reads tokens with its own streaming tokenizer rather than with encoding/json.Decoder. Values are
+// unmarshalled with encoding/json, and the methods of encoding/json.Decoder are provided with the same behavior;
+// invalid input is reported with a *json.SyntaxError. Decoder no longer embeds an encoding/json.Decoder, since one
+// reading the same input would consume bytes the tokenizer has not yet read. Code that used the methods of the
+// embedded Decoder can call the same methods on Decoder, and code that needs a *json.Decoder can take one over the
+// rest of the input with JSONDecoder.
 type Decoder struct {
-	json.Decoder
+	tok *tokenizer
 
-	path    JsonPath
+	path    pathStack
 	context jsonContext
+
+	input  *countingReader
+	limits Limits
+	err    error
+
+	dupMode    DuplicateKeyMode
+	dupHandler func(path JsonPath)
+	keys       []map[string]struct{}
+	dup        bool
+
+	useNumber             bool
+	disallowUnknownFields bool
 }
 
 // NewDecoder creates a new instance of the extended JSON Decoder.
 func NewDecoder(r io.Reader) *Decoder {
-	return &Decoder{Decoder: *json.NewDecoder(r)}
+	input := &countingReader{r: r}
+	return &Decoder{tok: newTokenizer(input), input: input}
+}
+
+// UseNumber causes the Decoder to unmarshal a number into an interface{} as a json.Number instead of as a float64.
+// This is equivalent to encoding/json.Decoder.UseNumber().
+func (d *Decoder) UseNumber() {
+	d.useNumber = true
+}
+
+// DisallowUnknownFields causes the Decoder to return an error when the destination is a struct and the input
+// contains object keys which do not match any non-ignored, exported fields in the destination. This is equivalent
+// to encoding/json.Decoder.DisallowUnknownFields().
+func (d *Decoder) DisallowUnknownFields() {
+	d.disallowUnknownFields = true
+}
+
+// More reports whether there is another element in the current array or object being parsed. This is equivalent
+// to encoding/json.Decoder.More().
+func (d *Decoder) More() bool {
+	return d.err == nil && d.tok.more()
+}
+
+// Buffered returns a reader of the data remaining in the Decoder's buffer. This is equivalent to
+// encoding/json.Decoder.Buffered().
+func (d *Decoder) Buffered() io.Reader {
+	return bytes.NewReader(d.tok.buf[d.tok.pos:])
+}
+
+// InputOffset returns the input stream byte offset of the current decoder position. This is equivalent to
+// encoding/json.Decoder.InputOffset().
+func (d *Decoder) InputOffset() int64 {
+	return d.tok.offset()
+}
+
+// JSONDecoder returns an encoding/json.Decoder that reads the input from the current position of the Decoder, with
+// the options set by UseNumber and DisallowUnknownFields. It replaces the Decoder that was embedded in earlier
+// versions, for code that requires a *json.Decoder. The two cannot share the input, so the Decoder must not be used
+// after JSONDecoder is called. The json.Decoder knows nothing of the tokens read before, so it should be taken
+// between top-level values, or used only to Decode the values that follow.
+func (d *Decoder) JSONDecoder() *json.Decoder {
+	rest := append([]byte(nil), d.tok.buf[d.tok.pos:]...)
+	d.tok.pos = len(d.tok.buf)
+	jd := json.NewDecoder(io.MultiReader(bytes.NewReader(rest), d.input))
+	if d.useNumber {
+		jd.UseNumber()
+	}
+	if d.disallowUnknownFields {
+		jd.DisallowUnknownFields()
+	}
+	return jd
 }
 
 // SeekTo causes the Decoder to move forward to a given path in the JSON structure.
@@ -47,7 +117,7 @@ func (d *Decoder) SeekTo(path ...interface{}) (bool, error) {
 	}
 
 	for {
-		if len(path) == len(d.path) && d.path.Equal(path) {
+		if d.path.equal(path) {
 			return true, nil
 		}
 		_, err := d.Token()
@@ -62,104 +132,171 @@ func (d *Decoder) SeekTo(path ...interface{}) (bool, error) {
 // Decode reads the next JSON-encoded value from its input and stores it in the value pointed to by v. This is
 // equivalent to encoding/json.Decode().
 func (d *Decoder) Decode(v interface{}) error {
+	raw, err := d.skip(true)
+	if err != nil {
+		return err
+	}
+	if !d.useNumber && !d.disallowUnknownFields {
+		return json.Unmarshal(raw, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if d.useNumber {
+		dec.UseNumber()
+	}
+	if d.disallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(v)
+}
+
+// skip consumes the next value without unmarshalling it. If capture is true the value is returned as it appears in
+// the input; the returned slice is only valid until the next read from the Decoder.
+func (d *Decoder) skip(capture bool) ([]byte, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	d.dup = false
+	raw, err := d.tok.skipValue(capture)
+	if err != nil {
+		return nil, d.readError(err)
+	}
 	switch d.context {
 	case objValue:
 		d.context = objKey
-		break
 	case arrValue:
 		d.path.incTop()
-		break
 	}
-	return d.Decoder.Decode(v)
+	return raw, nil
+}
+
+// skipLimited captures the next value as skip does, unless it is longer than max bytes, in which case it returns
+// errCaptureLimit without reading the rest of the value into memory.
+func (d *Decoder) skipLimited(max int) ([]byte, error) {
+	d.tok.maxCapture = max
+	defer func() { d.tok.maxCapture = 0 }()
+	raw, err := d.skip(true)
+	if err == nil && len(raw) > max {
+		return nil, errCaptureLimit
+	}
+	return raw, err
 }
 
 // Path returns a slice of string and/or int values representing the path from the root of the JSON object to the
 // position of the most-recently parsed token.
 func (d *Decoder) Path() JsonPath {
-	p := make(JsonPath, len(d.path))
-	copy(p, d.path)
-	return p
+	return d.path.jsonPath()
 }
 
 // Token is equivalent to the Token() method on json.Decoder. The primary difference is that it distinguishes
 // between strings that are keys and and strings that are values. String tokens that are object keys are returned as a
 // KeyString rather than as a native string.
+//
+// As with json.Decoder, Token returns io.EOF at the end of the input even if it ends within an object or array,
+// provided that it does not end within a token.
 func (d *Decoder) Token() (json.Token, error) {
-	t, err := d.Decoder.Token()
-	if err != nil {
-		return t, err
+	kind, err := d.next()
+	if err == io.ErrUnexpectedEOF && d.tok.atEnd() {
+		return nil, io.EOF
+	} else if err != nil {
+		return nil, err
 	}
 
-	if t == nil {
-		switch d.context {
-		case objValue:
-			d.context = objKey
-			break
-		case arrValue:
-			d.path.incTop()
-			break
+	switch kind {
+	case tokenObjectStart:
+		return json.Delim('{'), nil
+	case tokenObjectEnd:
+		return json.Delim('}'), nil
+	case tokenArrayStart:
+		return json.Delim('['), nil
+	case tokenArrayEnd:
+		return json.Delim(']'), nil
+	case tokenKey:
+		return KeyString(d.path.top().key), nil
+	}
+	return d.scalar(kind)
+}
+
+// scalar returns the value of the string, number or literal token that has just been read.
+func (d *Decoder) scalar(kind tokenKind) (interface{}, error) {
+	switch kind {
+	case tokenString:
+		return d.tok.string(), nil
+	case tokenNumber:
+		n, err := d.tok.number(d.useNumber)
+		if err != nil {
+			d.err = err
 		}
-		return t, err
+		return n, err
+	case tokenTrue:
+		return true, nil
+	case tokenFalse:
+		return false, nil
 	}
+	return nil, nil
+}
 
-	switch t := t.(type) {
-	case json.Delim:
-		switch t {
-		case json.Delim('{'):
-			if d.context == arrValue {
-				d.path.incTop()
-			}
-			d.path.push("")
-			d.context = objKey
-			break
-		case json.Delim('}'):
-			d.path.pop()
-			d.context = d.path.inferContext()
-			break
-		case json.Delim('['):
-			if d.context == arrValue {
-				d.path.incTop()
-			}
-			d.path.push(-1)
-			d.context = arrValue
-			break
-		case json.Delim(']'):
-			d.path.pop()
-			d.context = d.path.inferContext()
-			break
+// next reads the next token and updates the path without materializing the token's value.
+func (d *Decoder) next() (tokenKind, error) {
+	if d.err != nil {
+		return tokenNone, d.err
+	}
+	kind, err := d.tok.next()
+	if kind == tokenNone {
+		return kind, d.readError(err)
+	}
+	d.track(kind)
+	if err != nil {
+		return kind, d.readError(err)
+	}
+	if err = d.checkDuplicate(kind); err != nil {
+		return kind, err
+	}
+	return kind, nil
+}
+
+// track updates the path and context to account for a token of the given kind.
+func (d *Decoder) track(kind tokenKind) {
+	switch kind {
+	case tokenObjectStart:
+		if d.context == arrValue {
+			d.path.incTop()
 		}
-	case float64, json.Number, bool:
-		switch d.context {
-		case objValue:
-			d.context = objKey
-			break
-		case arrValue:
+		d.path.push(false)
+		d.context = objKey
+	case tokenArrayStart:
+		if d.context == arrValue {
 			d.path.incTop()
-			break
 		}
-		break
-	case string:
+		d.path.push(true)
+		d.context = arrValue
+	case tokenObjectEnd, tokenArrayEnd:
+		d.path.pop()
+		d.context = d.path.inferContext()
+	case tokenKey:
+		top := d.path.top()
+		top.key = d.tok.appendString(top.key[:0])
+		d.context = objValue
+	default:
 		switch d.context {
-		case objKey:
-			d.path.nameTop(t)
-			d.context = objValue
-			return KeyString(t), err
 		case objValue:
 			d.context = objKey
 		case arrValue:
 			d.path.incTop()
 		}
-		break
 	}
-
-	return t, err
 }
 
 // Scan moves forward over the JSON stream consuming all the tokens at the current level (current object, current array)
 // invoking each matching PathAction along the way.
 //
-// Scan returns true if there are more contiguous values to scan (for example in an array).
+// Scan returns true if there are more contiguous values to scan (for example in an array). The actions are compiled
+// by the first call and reused by later calls until another action is added.
 func (d *Decoder) Scan(ext *PathActions) (bool, error) {
+	return d.ScanMatcher(ext.Compile())
+}
+
+// ScanMatcher is equivalent to Scan using PathActions that have been compiled to a PathMatcher.
+func (d *Decoder) ScanMatcher(m *PathMatcher) (bool, error) {
 
 	rootPath := d.Path()
 
@@ -168,42 +305,116 @@ func (d *Decoder) Scan(ext *PathActions) (bool, error) {
 		rootPath.incTop()
 	}
 
+	return d.scan(rootPath, m, false)
+}
+
+// canSkip reports whether Scan may skip a value without reading its tokens individually. Values are not skipped when
+// every duplicate key must be reported.
+func (d *Decoder) canSkip() bool {
+	return d.dupMode == DuplicateKeysAllow || d.dupMode != DuplicateKeysError && d.dupHandler == nil
+}
+
+// scan implements Scan for paths relative to rootPath. If resume is true, the current position is matched before
+// any further tokens are read.
+func (d *Decoder) scan(rootPath JsonPath, m *PathMatcher, resume bool) (bool, error) {
+
+	// the trie nodes matching each level of the path below rootPath
+	state := matchState{m: m}
+	var node *matchNode
+	if len(d.path) > len(rootPath) {
+		node = d.matchElement(&state, d.path[len(rootPath):], state.reset(d.path[len(rootPath):]))
+	}
+
+	// values held back in DuplicateKeysLast mode
+	var deferred []deferredValue
+
 	for {
-		// advance the token position
-		_, err := d.Token()
-		if err != nil {
-			return false, err
-		}
+		if !resume {
+			// advance the token position
+			kind, err := d.next()
+			if err != nil {
+				return false, err
+			}
 
-	match:
-		var relPath JsonPath
+			// dispatch the values buffered for an object that has just closed
+			if kind == tokenObjectEnd && len(deferred) > 0 {
+				if deferred, err = d.dispatchDeferred(deferred, rootPath, m); err != nil {
+					return d.More(), err
+				}
+			}
 
-		// capture the new JSON path
-		path := d.Path()
+			if len(d.path) > len(rootPath) {
+				node = d.matchElement(&state, d.path[len(rootPath):],
+					state.update(d.path[len(rootPath):], kind == tokenObjectStart || kind == tokenArrayStart))
+			}
+		}
 
-		if len(path) > len(rootPath) {
-			// capture the path relative to where the scan started
-			relPath = path[len(rootPath):]
-		} else {
+	match:
+		if len(d.path) <= len(rootPath) {
 			// if the path is not longer than the root, then we are done with this scan
 			// return boolean flag indicating if there are more items to scan at the same level
-			return d.Decoder.More(), nil
+			return d.More(), nil
 		}
 
-		// match the relative path against the path actions
-		if node := ext.node.match(relPath); node != nil {
+		// skip the value of a repeated key so that its actions are not dispatched again
+		if d.dup && d.dupMode == DuplicateKeysFirst {
+			if _, err := d.skip(false); err != nil {
+				return d.More(), err
+			}
+			node = d.matchElement(&state, d.path[len(rootPath):], state.reset(d.path[len(rootPath):]))
+			continue
+		}
+
+		if node == nil && d.context == objValue && d.canSkip() {
+			// no action can match within the value of this key, so skip over it without tracking its path
+			if _, err := d.skip(false); err != nil {
+				return d.More(), err
+			}
+			continue
+		}
+		if node != nil {
+			if d.dupMode == DuplicateKeysLast && d.context == objValue && node.action != nil && !resume {
+				// a later occurrence of this key may replace the value, so hold it back until the object closes
+				var err error
+				if deferred, err = d.deferValue(deferred); err != nil {
+					return d.More(), err
+				}
+				continue
+			}
+			resume = false
 			if node.action != nil {
 				// we have a match so execute the action
-				err = node.action(d)
+				err := node.action(d)
 				if err != nil {
-					return d.Decoder.More(), err
+					return d.More(), err
 				}
-				// The action may have advanced the decoder. If we are in an array, advancing it further would
-				// skip tokens. So, if we are scanning an array, jump to the top without advancing the token.
-				if d.path.inferContext() == arrValue && d.Decoder.More() {
+				// The action may have advanced the decoder by any number of tokens, so match the path again.
+				if len(d.path) > len(rootPath) {
+					node = d.matchElement(&state, d.path[len(rootPath):], state.reset(d.path[len(rootPath):]))
+				}
+				// If we are in an array, advancing it further would skip tokens. So, if we are scanning an array,
+				// jump to the top without advancing the token.
+				if d.path.inferContext() == arrValue && d.More() {
 					goto match
 				}
 			}
 		}
+		resume = false
+	}
+}
+
+// matchElement adjusts node, the match for the current position, when the position is within an array. An action
+// for an array element is called before the element is read, so the position matches the element that follows, or
+// nothing at the end of the array. rel is the path relative to the root of the scan, which must not be empty.
+func (d *Decoder) matchElement(state *matchState, rel pathStack, node *matchNode) *matchNode {
+	if d.context != arrValue {
+		return node
+	}
+	if !d.More() {
+		return nil
 	}
+	rel.incTop()
+	node = state.matchFrom(len(rel), rel)
+	rel.top().index--
+	return node
 }
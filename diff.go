@@ -0,0 +1,326 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DiffKind identifies the kind of change reported by a DiffEvent.
+type DiffKind int
+
+const (
+	// DiffAdded reports a value that is only present in the second document.
+	DiffAdded DiffKind = iota
+	// DiffRemoved reports a value that is only present in the first document.
+	DiffRemoved
+	// DiffChanged reports a value that differs between the documents. Objects and arrays are compared member by
+	// member, so a DiffChanged value is a scalar or a value whose type differs.
+	DiffChanged
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffChanged:
+		return "changed"
+	}
+	return "DiffKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// DiffEvent is a difference between two documents found by a Differ.
+type DiffEvent struct {
+	Kind DiffKind
+	Path JsonPath        // the path of the value, from the root of the documents
+	Old  json.RawMessage // the value in the first document, or nil if it was added
+	New  json.RawMessage // the value in the second document, or nil if it was removed
+}
+
+// DefaultDiffBuffer is the number of bytes a Differ buffers for reordered object members if MaxBuffer is zero.
+const DefaultDiffBuffer = 16 << 20
+
+// ErrDiffBufferFull is returned by a Differ when the members it has buffered exceed its MaxBuffer.
+var ErrDiffBufferFull = errors.New("jsonpath: diff buffer full")
+
+// Differ compares two JSON documents as they are streamed from a pair of Decoders, reading both in lockstep so that
+// neither is held in memory.
+//
+// Objects are compared member by member, whatever the order of their keys. Members with the same key at the same
+// position are compared as they are read; a member whose key is at a different position in the other document is
+// buffered until its counterpart is read, or until the end of the object if there is none. Arrays are compared
+// element by element, so an element inserted into an array changes each element after it.
+//
+// Scalars are compared by value, so 1.0 is equal to 1 and "\u0041" to "A". Numbers are compared exactly, without
+// conversion to float64.
+type Differ struct {
+	// MaxBuffer is the maximum number of bytes of members held at one time while matching reordered keys. If it is
+	// zero, DefaultDiffBuffer is used.
+	MaxBuffer int
+}
+
+// differ holds the state of a Diff.
+type differ struct {
+	max      int
+	buffered int
+	fn       func(DiffEvent) error
+}
+
+// Diff reads the next value from each of a and b and calls fn with each difference between them, in the order they
+// are found. Paths are relative to the values read, so a difference between the values themselves has an empty path.
+//
+// If fn returns an error, Diff stops and returns it. If more than MaxBuffer bytes must be buffered, Diff returns an
+// error that wraps ErrDiffBufferFull.
+func (df *Differ) Diff(a, b *Decoder, fn func(DiffEvent) error) error {
+	s := &differ{max: df.MaxBuffer, fn: fn}
+	if s.max == 0 {
+		s.max = DefaultDiffBuffer
+	}
+	return s.value(a, b, JsonPath{})
+}
+
+// value compares the next values of a and b.
+func (s *differ) value(a, b *Decoder, path JsonPath) error {
+
+	ca, err := a.peekValue()
+	if err != nil {
+		return err
+	}
+	cb, err := b.peekValue()
+	if err != nil {
+		return err
+	}
+	if ca == cb && (ca == '{' || ca == '[') {
+		if _, err := a.next(); err != nil {
+			return err
+		}
+		if _, err := b.next(); err != nil {
+			return err
+		}
+		if ca == '{' {
+			return s.object(a, b, path)
+		}
+		return s.array(a, b, path)
+	}
+
+	raw, err := a.skip(true)
+	if err != nil {
+		return err
+	}
+	old := append(json.RawMessage(nil), raw...)
+	raw, err = b.skip(true)
+	if err != nil {
+		return err
+	}
+	if eq, err := jsonEqual(old, raw); err != nil || eq {
+		return err
+	}
+	return s.fn(DiffEvent{Kind: DiffChanged, Path: path, Old: old, New: append(json.RawMessage(nil), raw...)})
+}
+
+// object compares the members of objects whose starts have been read from a and b.
+func (s *differ) object(a, b *Decoder, path JsonPath) error {
+
+	// the members whose keys have been read from one document but not yet from the other
+	pendingA, pendingB := map[string][]byte{}, map[string][]byte{}
+	var orderA, orderB []string
+
+	moreA, moreB := true, true
+	for {
+		moreA = moreA && a.More()
+		moreB = moreB && b.More()
+		if !moreA && !moreB {
+			break
+		}
+		var keyA, keyB string
+		if moreA {
+			if _, err := a.next(); err != nil {
+				return err
+			}
+			keyA = string(a.path.top().key)
+		}
+		if moreB {
+			if _, err := b.next(); err != nil {
+				return err
+			}
+			keyB = string(b.path.top().key)
+		}
+
+		if moreA && moreB && keyA == keyB {
+			if err := s.value(a, b, append(path[:len(path):len(path)], keyA)); err != nil {
+				return err
+			}
+			continue
+		}
+		if moreA {
+			member := append(path[:len(path):len(path)], keyA)
+			if raw, ok := pendingB[keyA]; ok {
+				delete(pendingB, keyA)
+				s.buffered -= len(raw)
+				if err := s.value(a, NewDecoder(bytes.NewReader(raw)), member); err != nil {
+					return err
+				}
+			} else if err := s.buffer(a, keyA, pendingA, &orderA, member); err != nil {
+				return err
+			}
+		}
+		if moreB {
+			member := append(path[:len(path):len(path)], keyB)
+			if raw, ok := pendingA[keyB]; ok {
+				delete(pendingA, keyB)
+				s.buffered -= len(raw)
+				if err := s.value(NewDecoder(bytes.NewReader(raw)), b, member); err != nil {
+					return err
+				}
+			} else if err := s.buffer(b, keyB, pendingB, &orderB, member); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := a.next(); err != nil {
+		return err
+	}
+	if _, err := b.next(); err != nil {
+		return err
+	}
+
+	// the members that remain are only in one of the documents
+	for _, key := range orderA {
+		if raw, ok := pendingA[key]; ok {
+			s.buffered -= len(raw)
+			if err := s.fn(DiffEvent{Kind: DiffRemoved, Path: append(path[:len(path):len(path)], key), Old: raw}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, key := range orderB {
+		if raw, ok := pendingB[key]; ok {
+			s.buffered -= len(raw)
+			if err := s.fn(DiffEvent{Kind: DiffAdded, Path: append(path[:len(path):len(path)], key), New: raw}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// buffer reads the value of the member key from d into pending.
+func (s *differ) buffer(d *Decoder, key string, pending map[string][]byte, order *[]string, member JsonPath) error {
+	// the limit is checked as the member is read, so that a single large member is not held in full
+	var raw []byte
+	err := errCaptureLimit
+	if free := s.max - s.buffered + len(pending[key]); free > 0 {
+		raw, err = d.skipLimited(free)
+	}
+	if err == errCaptureLimit {
+		return fmt.Errorf("%w at %s", ErrDiffBufferFull, FormatPath(member))
+	} else if err != nil {
+		return err
+	}
+	s.buffered += len(raw)
+	if prev, ok := pending[key]; ok {
+		// a repeated key replaces the earlier member
+		s.buffered -= len(prev)
+	} else {
+		*order = append(*order, key)
+	}
+	pending[key] = append([]byte(nil), raw...)
+	return nil
+}
+
+// array compares the elements of arrays whose starts have been read from a and b.
+func (s *differ) array(a, b *Decoder, path JsonPath) error {
+
+	for i := 0; ; i++ {
+		moreA, moreB := a.More(), b.More()
+		if !moreA && !moreB {
+			break
+		}
+		elem := append(path[:len(path):len(path)], i)
+		if moreA && moreB {
+			if err := s.value(a, b, elem); err != nil {
+				return err
+			}
+			continue
+		}
+
+		d, kind := a, DiffRemoved
+		if moreB {
+			d, kind = b, DiffAdded
+		}
+		raw, err := d.skip(true)
+		if err != nil {
+			return err
+		}
+		e := DiffEvent{Kind: kind, Path: elem}
+		if kind == DiffRemoved {
+			e.Old = append(json.RawMessage(nil), raw...)
+		} else {
+			e.New = append(json.RawMessage(nil), raw...)
+		}
+		if err := s.fn(e); err != nil {
+			return err
+		}
+	}
+	if _, err := a.next(); err != nil {
+		return err
+	}
+	_, err := b.next()
+	return err
+}
+
+// Patch reads the next value from each of a and b and writes an RFC 6902 JSON Patch that transforms the first into
+// the second to e, as an array of add, remove and replace operations. The differences are found as by Diff.
+func (df *Differ) Patch(a, b *Decoder, e *Encoder) error {
+
+	if err := e.WriteToken(json.Delim('[')); err != nil {
+		return err
+	}
+
+	// array elements are removed from the end of an array in increasing index order, and each removal moves the
+	// elements after it, so every removal in a run targets the index of the first
+	var run JsonPath
+	err := df.Diff(a, b, func(ev DiffEvent) error {
+		op := PatchOperation{Path: formatPointer(ev.Path), Value: ev.New}
+		switch ev.Kind {
+		case DiffAdded:
+			op.Op = "add"
+		case DiffChanged:
+			op.Op = "replace"
+		case DiffRemoved:
+			op.Op = "remove"
+			if _, ok := ev.Path[len(ev.Path)-1].(int); ok {
+				parent := ev.Path[:len(ev.Path)-1]
+				if run != nil && parent.Equal(run[:len(run)-1]) {
+					op.Path = formatPointer(run)
+				} else {
+					run = ev.Path
+				}
+			}
+		}
+		return e.WriteValue(op)
+	})
+	if err != nil {
+		return err
+	}
+	return e.WriteToken(json.Delim(']'))
+}
+
+// formatPointer returns the RFC 6901 JSON Pointer for path.
+func formatPointer(path JsonPath) string {
+	var b strings.Builder
+	for _, e := range path {
+		b.WriteByte('/')
+		if key, ok := e.(string); ok {
+			b.WriteString(escapePointer(key))
+		} else {
+			b.WriteString(strconv.Itoa(e.(int)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,133 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	diffA = `{"id":1,"name":"a","tags":["x","y","z"],"meta":{"n":1.0,"k":"v"},"gone":true,"lines":[{"q":1}]}`
+	diffB = `{"id":1,"meta":{"k":"w","n":1},"tags":["x","q"],"lines":[{"q":2},{"q":3}],"name":"a","new":null}`
+)
+
+func diffStrings(t *testing.T, df *Differ, a, b string) ([]string, error) {
+	var events []string
+	err := df.Diff(NewDecoder(strings.NewReader(a)), NewDecoder(strings.NewReader(b)), func(e DiffEvent) error {
+		events = append(events, e.Kind.String()+" "+FormatPath(e.Path)+" "+string(e.Old)+" "+string(e.New))
+		return nil
+	})
+	return events, err
+}
+
+func TestDiff(t *testing.T) {
+
+	events, err := diffStrings(t, &Differ{}, diffA, diffB)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`changed $.tags[1] "y" "q"`,
+		`removed $.tags[2] "z" `,
+		`changed $.meta.k "v" "w"`,
+		`changed $.lines[0].q 1 2`,
+		`added $.lines[1]  {"q":3}`,
+		`removed $.gone true `,
+		`added $.new  null`,
+	}, events)
+
+	events, err = diffStrings(t, &Differ{}, diffA, diffA)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	events, err = diffStrings(t, &Differ{}, `{"a":[1]}`, `{"a":{"0":1}}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`changed $.a [1] {"0":1}`}, events)
+
+	events, err = diffStrings(t, &Differ{}, `1`, `"1"`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`changed $ 1 "1"`}, events)
+
+	// integers that round to the same float64 differ
+	events, err = diffStrings(t, &Differ{}, `{"id":9007199254740993}`, `{"id":9007199254740992}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`changed $.id 9007199254740993 9007199254740992`}, events)
+
+	// numbers are compared without expanding their exponents
+	events, err = diffStrings(t, &Differ{}, `[1e9999999,1e-9999999]`, `[10E9999998,1e-9999998]`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`changed $[1] 1e-9999999 1e-9999998`}, events)
+}
+
+func TestDiffBuffer(t *testing.T) {
+
+	// a and b of the first document are both buffered before their counterparts are read
+	a := `{"a":"0123456789","b":"0123456789","c":1}`
+	b := `{"c":1,"a":"0123456789","b":"0123456789"}`
+	events, err := diffStrings(t, &Differ{MaxBuffer: 25}, a, b)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	_, err = diffStrings(t, &Differ{MaxBuffer: 24}, a, b)
+	assert.True(t, errors.Is(err, ErrDiffBufferFull))
+	assert.EqualError(t, err, "jsonpath: diff buffer full at $.b")
+
+	// a member larger than the buffer is not read in full
+	r := strings.NewReader(`{"a":"` + strings.Repeat("x", 1<<20) + `","b":1}`)
+	err = (&Differ{MaxBuffer: 1000}).Diff(NewDecoder(r), NewDecoder(strings.NewReader(`{"b":1,"a":"x"}`)),
+		func(DiffEvent) error { return nil })
+	assert.True(t, errors.Is(err, ErrDiffBufferFull))
+	assert.EqualError(t, err, "jsonpath: diff buffer full at $.a")
+	assert.True(t, r.Len() > 1<<19, "%d bytes unread", r.Len())
+}
+
+func TestDiffErrors(t *testing.T) {
+
+	stop := errors.New("stop")
+	n := 0
+	err := (&Differ{}).Diff(NewDecoder(strings.NewReader(diffA)), NewDecoder(strings.NewReader(diffB)),
+		func(DiffEvent) error {
+			n++
+			return stop
+		})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, n)
+
+	_, err = diffStrings(t, &Differ{}, `{"a":[1,}`, `{"a":[1,2]}`)
+	assert.Error(t, err)
+	_, err = diffStrings(t, &Differ{}, ``, `1`)
+	assert.Error(t, err)
+}
+
+func TestDiffPatch(t *testing.T) {
+
+	for _, tst := range []struct{ a, b string }{
+		{diffA, diffB},
+		{`{"a":[1,2,3,4,5]}`, `{"a":[1,2]}`},
+		{`[[1,2,3],[4,5,6]]`, `[[1],[4]]`},
+		{`{"a~b":{"c/d":1}}`, `{"a~b":{"c/d":2}}`},
+		{`[1]`, `{"a":1}`},
+	} {
+		var patch bytes.Buffer
+		err := (&Differ{}).Patch(NewDecoder(strings.NewReader(tst.a)), NewDecoder(strings.NewReader(tst.b)),
+			NewEncoder(&patch))
+		require.NoError(t, err, tst.a)
+
+		var p Patch
+		require.NoError(t, json.Unmarshal(patch.Bytes(), &p), patch.String())
+		var out bytes.Buffer
+		require.NoError(t, p.Apply(NewDecoder(strings.NewReader(tst.a)), NewEncoder(&out)), patch.String())
+		eq, err := jsonEqual(out.Bytes(), []byte(tst.b))
+		require.NoError(t, err)
+		assert.True(t, eq, "%s: patch %s gives %s", tst.a, patch.String(), out.String())
+	}
+
+	var patch bytes.Buffer
+	err := (&Differ{}).Patch(NewDecoder(strings.NewReader(`{"a":[1,2,3]}`)), NewDecoder(strings.NewReader(`{"a":[1]}`)),
+		NewEncoder(&patch))
+	require.NoError(t, err)
+	assert.Equal(t, `[{"op":"remove","path":"/a/1"},{"op":"remove","path":"/a/1"}]`+"\n", patch.String())
+}
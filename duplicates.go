@@ -0,0 +1,148 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// DuplicateKeyMode specifies how the Decoder treats a key that appears more than once in the same JSON object.
+type DuplicateKeyMode int
+
+const (
+	// DuplicateKeysAllow disables duplicate key detection. This is the default.
+	DuplicateKeysAllow DuplicateKeyMode = iota
+	// DuplicateKeysError causes the Decoder to return a *DuplicateKeyError when a key is repeated.
+	DuplicateKeysError
+	// DuplicateKeysWarn reports each repeated key to the handler set with SetDuplicateKeyHandler and otherwise
+	// accepts it.
+	DuplicateKeysWarn
+	// DuplicateKeysFirst causes Scan to dispatch PathActions for the first occurrence of a key only. The values
+	// of later occurrences are skipped.
+	DuplicateKeysFirst
+	// DuplicateKeysLast causes Scan to dispatch PathActions for the last occurrence of a key only. Since a later
+	// occurrence may appear anywhere in the object, the value of each key with an action is held in memory until
+	// the enclosing object closes, when the action is dispatched, so memory use grows with the size of those
+	// values. Values that only contain paths with actions are scanned as they are read, and the actions within
+	// each occurrence of such a key are dispatched.
+	DuplicateKeysLast
+)
+
+// DuplicateKeyError is returned by the Decoder in DuplicateKeysError mode when a key is repeated within an object.
+type DuplicateKeyError struct {
+	Path JsonPath // path of the repeated key
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("jsonpath: duplicate key %q at %v", e.Path[len(e.Path)-1], e.Path)
+}
+
+// SetDuplicateKeyMode enables detection of keys that are repeated within a JSON object.
+//
+// Detection covers keys read with Token, SeekTo and Scan. Objects consumed with Decode are not inspected.
+func (d *Decoder) SetDuplicateKeyMode(mode DuplicateKeyMode) {
+	d.dupMode = mode
+}
+
+// SetDuplicateKeyHandler sets a function to be called with the path of each repeated key. The handler is called in
+// every mode other than DuplicateKeysAllow and DuplicateKeysError.
+func (d *Decoder) SetDuplicateKeyHandler(fn func(path JsonPath)) {
+	d.dupHandler = fn
+}
+
+// checkDuplicate records the keys seen at each object level and reports the most recently parsed token if it is a
+// key that repeats an earlier key in the same object.
+func (d *Decoder) checkDuplicate(kind tokenKind) error {
+	d.dup = false
+	if d.dupMode == DuplicateKeysAllow {
+		return nil
+	}
+
+	// keep the key sets aligned with the path stack
+	depth := len(d.path)
+	for len(d.keys) < depth {
+		d.keys = append(d.keys, nil)
+	}
+	d.keys = d.keys[:depth]
+
+	switch kind {
+	case tokenObjectStart:
+		if seen := d.keys[depth-1]; seen != nil {
+			for k := range seen {
+				delete(seen, k)
+			}
+		} else {
+			d.keys[depth-1] = map[string]struct{}{}
+		}
+	case tokenArrayStart:
+		d.keys[depth-1] = nil
+	case tokenKey:
+		seen := d.keys[depth-1]
+		if seen == nil {
+			// the object was entered before detection was enabled
+			seen = map[string]struct{}{}
+			d.keys[depth-1] = seen
+		}
+		key := d.path.top().key
+		if _, ok := seen[string(key)]; !ok {
+			seen[string(key)] = struct{}{}
+			return nil
+		}
+		d.dup = true
+		if d.dupMode == DuplicateKeysError {
+			d.err = &DuplicateKeyError{Path: d.Path()}
+			return d.err
+		}
+		if d.dupHandler != nil {
+			d.dupHandler(d.Path())
+		}
+	}
+	return nil
+}
+
+// deferredValue is the buffered value of an object key held back in DuplicateKeysLast mode.
+type deferredValue struct {
+	path JsonPath
+	raw  json.RawMessage
+}
+
+// deferValue buffers the value at the current key, replacing any earlier value buffered for the same key.
+func (d *Decoder) deferValue(deferred []deferredValue) ([]deferredValue, error) {
+	path := d.Path()
+	raw, err := d.skip(true)
+	if err != nil {
+		return deferred, err
+	}
+	for i, v := range deferred {
+		if v.path.Equal(path) {
+			deferred = append(deferred[:i], deferred[i+1:]...)
+			break
+		}
+	}
+	return append(deferred, deferredValue{path: path, raw: append(json.RawMessage(nil), raw...)}), nil
+}
+
+// dispatchDeferred scans the buffered values belonging to objects that have been closed, invoking the matching
+// PathActions. The remaining values are returned.
+func (d *Decoder) dispatchDeferred(deferred []deferredValue, rootPath JsonPath, m *PathMatcher) ([]deferredValue, error) {
+	depth := len(d.path)
+	remaining := deferred[:0]
+	for i, v := range deferred {
+		if len(v.path) <= depth {
+			remaining = append(remaining, v)
+			continue
+		}
+		sub := NewDecoder(bytes.NewReader(v.raw))
+		sub.path.set(v.path)
+		sub.context = objValue
+		sub.dupMode = d.dupMode
+		sub.dupHandler = d.dupHandler
+		sub.useNumber = d.useNumber
+		sub.disallowUnknownFields = d.disallowUnknownFields
+		if _, err := sub.scan(rootPath, m, true); err != nil && err != io.EOF {
+			return append(remaining, deferred[i+1:]...), err
+		}
+	}
+	return remaining, nil
+}
@@ -0,0 +1,122 @@
+package jsonpath
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var duplicatesJSON = []byte(`
+	{
+		"id": 1,
+		"items": [
+			{"sku": "a", "qty": 1, "qty": 2},
+			{"sku": "b", "qty": 3}
+		],
+		"total": {"amount": 10},
+		"id": 2,
+		"total": {"amount": 20, "amount": 30}
+	}`)
+
+func TestDuplicateKeysError(t *testing.T) {
+
+	d := NewDecoder(bytes.NewReader(duplicatesJSON))
+	d.SetDuplicateKeyMode(DuplicateKeysError)
+
+	err := readAllTokens(d)
+	assert.Equal(t, &DuplicateKeyError{Path: JsonPath{"items", 0, "qty"}}, err)
+	assert.EqualError(t, err, `jsonpath: duplicate key "qty" at [items 0 qty]`)
+}
+
+func TestDuplicateKeysWarn(t *testing.T) {
+
+	var dups []JsonPath
+	d := NewDecoder(bytes.NewReader(duplicatesJSON))
+	d.SetDuplicateKeyMode(DuplicateKeysWarn)
+	d.SetDuplicateKeyHandler(func(path JsonPath) {
+		dups = append(dups, path)
+	})
+
+	require.NoError(t, readAllTokens(d))
+	assert.Equal(t, []JsonPath{
+		{"items", 0, "qty"},
+		{"id"},
+		{"total"},
+		{"total", "amount"},
+	}, dups)
+}
+
+func TestDuplicateKeysAllow(t *testing.T) {
+
+	d := NewDecoder(bytes.NewReader(duplicatesJSON))
+	d.SetDuplicateKeyHandler(func(path JsonPath) {
+		t.Errorf("unexpected duplicate at %v", path)
+	})
+	require.NoError(t, readAllTokens(d))
+}
+
+func duplicateKeyActions(out *[]string) *PathActions {
+	record := func(d *Decoder) error {
+		var v interface{}
+		err := d.Decode(&v)
+		*out = append(*out, fmt.Sprintf("%v=%v", d.Path(), v))
+		return err
+	}
+	actions := &PathActions{}
+	actions.Add(record, "id")
+	actions.Add(record, "items", AnyIndex, "qty")
+	actions.Add(record, "total", "amount")
+	return actions
+}
+
+func TestDuplicateKeysScan(t *testing.T) {
+
+	tests := []struct {
+		mode DuplicateKeyMode
+		out  []string
+	}{
+		{
+			mode: DuplicateKeysAllow,
+			out:  []string{"[id]=1", "[items 0 qty]=1", "[items 0 qty]=2", "[items 1 qty]=3", "[total amount]=10", "[id]=2", "[total amount]=20", "[total amount]=30"},
+		},
+		{
+			mode: DuplicateKeysFirst,
+			out:  []string{"[id]=1", "[items 0 qty]=1", "[items 1 qty]=3", "[total amount]=10"},
+		},
+		{
+			mode: DuplicateKeysLast,
+			out:  []string{"[items 0 qty]=2", "[items 1 qty]=3", "[total amount]=10", "[total amount]=30", "[id]=2"},
+		},
+	}
+
+	for _, tst := range tests {
+		var out []string
+		d := NewDecoder(bytes.NewReader(duplicatesJSON))
+		d.SetDuplicateKeyMode(tst.mode)
+
+		_, err := d.Scan(duplicateKeyActions(&out))
+		require.NoError(t, err)
+		assert.Equal(t, tst.out, out, "mode %v", tst.mode)
+	}
+}
+
+func TestDuplicateKeysLastStreams(t *testing.T) {
+
+	// the array contains matches but has no action itself, so its elements are read from the input as they arrive
+	var offsets []int64
+	actions := &PathActions{}
+	actions.Add(func(d *Decoder) error {
+		offsets = append(offsets, d.InputOffset())
+		_, err := d.skip(false)
+		return err
+	}, "items", AnyIndex)
+
+	d := NewDecoder(bytes.NewBufferString(`{"items":[1,2],"n":0}`))
+	d.SetDuplicateKeyMode(DuplicateKeysLast)
+	_, err := d.Scan(actions)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{10, 11}, offsets)
+}
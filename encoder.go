@@ -0,0 +1,292 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Encoder writes a stream of JSON tokens to an io.Writer, tracking the path of each token in the same way as the
+// Decoder. It is the counterpart of Decoder.Token: tokens read from a Decoder can be written to an Encoder one at
+// a time, and Encoder.Path reports the same path as Decoder.Path at each step.
+//
+// The Encoder validates the structure of its output. A token that would make the output invalid JSON is rejected
+// with an *EncoderError and nothing is written, so the Encoder may continue to be used. Errors from the underlying
+// writer are returned from every subsequent call.
+//
+// Like encoding/json.Encoder, the Encoder writes a newline after each complete top-level value.
+type Encoder struct {
+	w   io.Writer
+	buf []byte
+
+	// values are marshalled with val and enc
+	val bytes.Buffer
+	enc *json.Encoder
+
+	path    pathStack
+	context jsonContext
+	empty   bool // the current object or array has no elements yet
+
+	prefix string
+	indent string
+	err    error
+}
+
+// EncoderError is returned by the Encoder when a token would make its output invalid JSON.
+type EncoderError struct {
+	Msg  string
+	Path JsonPath // path of the most recently written token
+}
+
+func (e *EncoderError) Error() string {
+	return fmt.Sprintf("jsonpath: %s at %v", e.Msg, e.Path)
+}
+
+// NewEncoder creates a new Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	e := &Encoder{w: w}
+	e.enc = json.NewEncoder(&e.val)
+	return e
+}
+
+// SetIndent instructs the Encoder to begin each object key and array element on a new line beginning with prefix
+// followed by one or more copies of indent according to its nesting depth. This is equivalent to
+// encoding/json.Encoder.SetIndent(). Calling SetIndent("", "") restores compact output.
+func (e *Encoder) SetIndent(prefix, indent string) {
+	e.prefix = prefix
+	e.indent = indent
+}
+
+// SetEscapeHTML specifies whether problematic HTML characters should be escaped inside JSON quoted strings. This is
+// equivalent to encoding/json.Encoder.SetEscapeHTML().
+func (e *Encoder) SetEscapeHTML(on bool) {
+	e.enc.SetEscapeHTML(on)
+}
+
+// Path returns a slice of string and/or int values representing the path of the most recently written token, as
+// Decoder.Path does for the most recently read token.
+func (e *Encoder) Path() JsonPath {
+	return e.path.jsonPath()
+}
+
+// WriteToken writes a single token, which may be any token returned by Decoder.Token or encoding/json.Decoder.Token:
+// a json.Delim, a KeyString, a string, a float64, a json.Number, a bool or nil. A string is written as an object
+// key when a key is expected. Any other value is written with WriteValue.
+func (e *Encoder) WriteToken(t json.Token) error {
+	switch t := t.(type) {
+	case json.Delim:
+		switch t {
+		case '{', '[':
+			return e.open(t)
+		case '}', ']':
+			return e.close(t)
+		}
+		return e.invalid(fmt.Sprintf("invalid delimiter %q", rune(t)))
+	case KeyString:
+		return e.WriteKey(string(t))
+	case string:
+		if e.context == objKey {
+			return e.WriteKey(t)
+		}
+	}
+	return e.WriteValue(t)
+}
+
+// WriteKey writes an object key. The next token must be the key's value.
+func (e *Encoder) WriteKey(key string) error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.context != objKey {
+		return e.invalid(fmt.Sprintf("unexpected object key %q", key))
+	}
+	b, err := e.marshal(key)
+	if err != nil {
+		return err
+	}
+	return e.writeKey(key, b)
+}
+
+// writeKey writes an object key that has already been encoded as raw.
+func (e *Encoder) writeKey(key string, raw []byte) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.separate()
+	e.buf = append(e.buf, raw...)
+	e.buf = append(e.buf, ':')
+	if e.indenting() {
+		e.buf = append(e.buf, ' ')
+	}
+	top := e.path.top()
+	top.key = append(top.key[:0], key...)
+	e.context = objValue
+	return e.flush()
+}
+
+// WriteValue writes a complete value, marshalled with encoding/json. The value may be an object key's value, an
+// array element or a top-level value. When indenting, objects and arrays within the value are indented to match
+// the surrounding output.
+func (e *Encoder) WriteValue(v interface{}) error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.context == objKey {
+		return e.invalid("expected object key")
+	}
+	b, err := e.marshal(v)
+	if err != nil {
+		return err
+	}
+
+	e.beginValue()
+	e.buf = append(e.buf, b...)
+	e.endValue()
+	return e.flush()
+}
+
+// writeRaw writes a complete value that has already been encoded as raw. When indenting, objects and arrays within
+// the value are re-indented; otherwise the value is written as it is.
+func (e *Encoder) writeRaw(raw []byte) error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.context == objKey {
+		return e.invalid("expected object key")
+	}
+	if e.indenting() && len(raw) > 0 && (raw[0] == '{' || raw[0] == '[') {
+		e.val.Reset()
+		if err := json.Indent(&e.val, raw, e.prefix+strings.Repeat(e.indent, len(e.path)), e.indent); err != nil {
+			return err
+		}
+		raw = e.val.Bytes()
+	}
+
+	e.beginValue()
+	e.buf = append(e.buf, raw...)
+	e.endValue()
+	return e.flush()
+}
+
+// Close reports an error if an object or array is still open. It does not close the underlying writer.
+func (e *Encoder) Close() error {
+	if e.err != nil {
+		return e.err
+	}
+	if len(e.path) > 0 {
+		return e.invalid("unexpected end of output")
+	}
+	return nil
+}
+
+func (e *Encoder) open(delim json.Delim) error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.context == objKey {
+		return e.invalid("expected object key")
+	}
+
+	e.beginValue()
+	e.buf = append(e.buf, byte(delim))
+	e.path.push(delim == '[')
+	e.context = e.path.inferContext()
+	e.empty = true
+	return e.flush()
+}
+
+func (e *Encoder) close(delim json.Delim) error {
+	if e.err != nil {
+		return e.err
+	}
+	if len(e.path) == 0 || e.path.top().array != (delim == ']') {
+		return e.invalid(fmt.Sprintf("unexpected %q", rune(delim)))
+	}
+	if e.context == objValue {
+		return e.invalid("expected value for object key")
+	}
+
+	if !e.empty {
+		e.newline(len(e.path) - 1)
+	}
+	e.buf = append(e.buf, byte(delim))
+	e.path.pop()
+	e.context = e.path.inferContext()
+	e.endValue()
+	return e.flush()
+}
+
+// beginValue writes the separator preceding a value and updates the path.
+func (e *Encoder) beginValue() {
+	if e.context == arrValue {
+		e.separate()
+		e.path.incTop()
+	}
+}
+
+// endValue updates the context after a complete value has been written.
+func (e *Encoder) endValue() {
+	switch e.context {
+	case objValue:
+		e.context = objKey
+	case none:
+		e.buf = append(e.buf, '\n')
+	}
+	e.empty = false
+}
+
+// separate writes the comma and indentation preceding an object key or array element.
+func (e *Encoder) separate() {
+	if !e.empty {
+		e.buf = append(e.buf, ',')
+	}
+	e.empty = false
+	e.newline(len(e.path))
+}
+
+func (e *Encoder) indenting() bool {
+	return e.prefix != "" || e.indent != ""
+}
+
+// newline begins a new line indented to the given depth.
+func (e *Encoder) newline(depth int) {
+	if !e.indenting() {
+		return
+	}
+	e.buf = append(e.buf, '\n')
+	e.buf = append(e.buf, e.prefix...)
+	for i := 0; i < depth; i++ {
+		e.buf = append(e.buf, e.indent...)
+	}
+}
+
+// marshal encodes v, indented to the current depth. The result is valid until the next call.
+func (e *Encoder) marshal(v interface{}) ([]byte, error) {
+	if e.indenting() {
+		e.enc.SetIndent(e.prefix+strings.Repeat(e.indent, len(e.path)), e.indent)
+	} else {
+		e.enc.SetIndent("", "")
+	}
+	e.val.Reset()
+	if err := e.enc.Encode(v); err != nil {
+		return nil, err
+	}
+	b := e.val.Bytes()
+	return b[:len(b)-1], nil
+}
+
+// flush writes the buffered output.
+func (e *Encoder) flush() error {
+	_, err := e.w.Write(e.buf)
+	e.buf = e.buf[:0]
+	if err != nil {
+		e.err = err
+	}
+	return err
+}
+
+func (e *Encoder) invalid(msg string) error {
+	return &EncoderError{Msg: msg, Path: e.Path()}
+}
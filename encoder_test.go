@@ -0,0 +1,171 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var encoderRoundTrip = []string{
+	`{}`,
+	`[]`,
+	`"top"`,
+	`{"a":1,"b":[true,false,null],"c":{}}`,
+	`{"nested":{"a":[{"b":[[],[{}]]}]},"s":"esc\"aped"}`,
+	`[0,-1.5,"x",[1,[2,[3]]],{"k":{"v":[]}}]`,
+}
+
+// copyTokens copies every token from d to e, checking that the paths agree after each token.
+func copyTokens(t *testing.T, d *Decoder, e *Encoder) {
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.NoError(t, e.WriteToken(tok))
+		assert.Equal(t, d.Path(), e.Path())
+	}
+	assert.NoError(t, e.Close())
+}
+
+func TestEncoderRoundTrip(t *testing.T) {
+
+	for _, in := range encoderRoundTrip {
+		var out bytes.Buffer
+		copyTokens(t, NewDecoder(strings.NewReader(in)), NewEncoder(&out))
+		assert.Equal(t, in+"\n", out.String())
+	}
+}
+
+func TestEncoderIndent(t *testing.T) {
+
+	for _, in := range encoderRoundTrip {
+		var exp bytes.Buffer
+		require.NoError(t, json.Indent(&exp, []byte(in), "> ", "\t"))
+
+		var out bytes.Buffer
+		e := NewEncoder(&out)
+		e.SetIndent("> ", "\t")
+		copyTokens(t, NewDecoder(strings.NewReader(in)), e)
+		assert.Equal(t, exp.String()+"\n", out.String(), in)
+	}
+}
+
+func TestEncoderWriteValue(t *testing.T) {
+
+	var out bytes.Buffer
+	e := NewEncoder(&out)
+	e.SetIndent("", "  ")
+
+	require.NoError(t, e.WriteToken(json.Delim('{')))
+	require.NoError(t, e.WriteKey("a"))
+	require.NoError(t, e.WriteValue(map[string]interface{}{"b": []int{1, 2}}))
+	assert.Equal(t, JsonPath{"a"}, e.Path())
+	require.NoError(t, e.WriteKey("c"))
+	require.NoError(t, e.WriteToken(json.Delim('[')))
+	assert.Equal(t, JsonPath{"c", -1}, e.Path())
+	require.NoError(t, e.WriteValue("x"))
+	assert.Equal(t, JsonPath{"c", 0}, e.Path())
+	require.NoError(t, e.WriteValue(json.RawMessage(`{ "d" : 1 }`)))
+	assert.Equal(t, JsonPath{"c", 1}, e.Path())
+	require.NoError(t, e.WriteToken(json.Delim(']')))
+	require.NoError(t, e.WriteToken(json.Delim('}')))
+	require.NoError(t, e.WriteValue(2))
+	require.NoError(t, e.Close())
+
+	assert.Equal(t, `{
+  "a": {
+    "b": [
+      1,
+      2
+    ]
+  },
+  "c": [
+    "x",
+    {
+      "d": 1
+    }
+  ]
+}
+2
+`, out.String())
+}
+
+func TestEncoderEscapeHTML(t *testing.T) {
+
+	var out bytes.Buffer
+	e := NewEncoder(&out)
+	e.SetEscapeHTML(false)
+	require.NoError(t, e.WriteToken(json.Delim('{')))
+	require.NoError(t, e.WriteKey("<a>"))
+	require.NoError(t, e.WriteValue("&"))
+	require.NoError(t, e.WriteToken(json.Delim('}')))
+	assert.Equal(t, `{"<a>":"&"}`+"\n", out.String())
+}
+
+func TestEncoderInvalid(t *testing.T) {
+
+	tests := []struct {
+		tokens []json.Token
+		msg    string
+		path   JsonPath
+	}{
+		{tokens: []json.Token{json.Delim('}')}, msg: `unexpected '}'`, path: JsonPath{}},
+		{tokens: []json.Token{json.Delim('['), json.Delim('}')}, msg: `unexpected '}'`, path: JsonPath{-1}},
+		{tokens: []json.Token{json.Delim('{'), json.Delim(']')}, msg: `unexpected ']'`, path: JsonPath{""}},
+		{tokens: []json.Token{json.Delim('{'), 1.0}, msg: "expected object key", path: JsonPath{""}},
+		{tokens: []json.Token{json.Delim('{'), json.Delim('[')}, msg: "expected object key", path: JsonPath{""}},
+		{tokens: []json.Token{json.Delim('{'), KeyString("a"), json.Delim('}')}, msg: "expected value for object key", path: JsonPath{"a"}},
+		{tokens: []json.Token{json.Delim('['), KeyString("a")}, msg: `unexpected object key "a"`, path: JsonPath{-1}},
+		{tokens: []json.Token{KeyString("a")}, msg: `unexpected object key "a"`, path: JsonPath{}},
+		{tokens: []json.Token{json.Delim('(')}, msg: `invalid delimiter '('`, path: JsonPath{}},
+	}
+
+	for _, tst := range tests {
+		var out bytes.Buffer
+		e := NewEncoder(&out)
+		var err error
+		for _, tok := range tst.tokens {
+			if err = e.WriteToken(tok); err != nil {
+				break
+			}
+		}
+		if assert.IsType(t, &EncoderError{}, err, "%v", tst.tokens) {
+			assert.Equal(t, tst.msg, err.(*EncoderError).Msg, "%v", tst.tokens)
+			assert.Equal(t, tst.path, err.(*EncoderError).Path, "%v", tst.tokens)
+		}
+	}
+}
+
+func TestEncoderRecoversFromInvalidToken(t *testing.T) {
+
+	var out bytes.Buffer
+	e := NewEncoder(&out)
+	require.NoError(t, e.WriteToken(json.Delim('[')))
+	assert.Error(t, e.WriteToken(json.Delim('}')))
+	assert.Error(t, e.WriteValue(func() {}))
+	assert.Error(t, e.Close())
+	require.NoError(t, e.WriteValue(1))
+	require.NoError(t, e.WriteToken(json.Delim(']')))
+	assert.NoError(t, e.Close())
+	assert.Equal(t, "[1]\n", out.String())
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) { return 0, errors.New("write failed") }
+
+func TestEncoderWriteError(t *testing.T) {
+
+	e := NewEncoder(failWriter{})
+	assert.EqualError(t, e.WriteToken(json.Delim('[')), "write failed")
+	assert.EqualError(t, e.WriteValue(1), "write failed")
+	assert.EqualError(t, e.Close(), "write failed")
+}
@@ -0,0 +1,70 @@
+package jsonpath
+
+import (
+	"errors"
+	"io"
+	"reflect"
+)
+
+// errScanDone is returned by an action to stop a scan once every value it needs has been read.
+var errScanDone = errors.New("jsonpath: scan done")
+
+// Extract reads a JSON value from r and returns the values at the given paths, decoded as by encoding/json into an
+// interface{}. The result is keyed by the paths as formatted by FormatPath, for example
+//
+//	values, err := Extract(r, JsonPath{"order", "id"}, JsonPath{"order", "lines", AnyIndex, "sku"})
+//	// values["$.order.id"] is "A-1" and values["$.order.lines[*].sku"] is []interface{}{"x1", "x2"}
+//
+// The value for a path containing AnyIndex is a []interface{} holding every matching value in the order they
+// appear. Paths that match nothing are absent from the result.
+//
+// The paths are matched in a single pass over the input. When none of the paths contains AnyIndex, reading stops as
+// soon as a value has been found for every path, so the remainder of the input, which may be incomplete or invalid,
+// is not read.
+func Extract(r io.Reader, paths ...JsonPath) (map[string]interface{}, error) {
+
+	fields := make([]*pathField, 0, len(paths))
+	var exact []*pathField
+	for _, p := range paths {
+		f := &pathField{name: FormatPath(p), path: p}
+		f.expr = f.name
+		for _, e := range p {
+			if e == AnyIndex {
+				f.appends = true
+			}
+		}
+		if f.appends {
+			f.value = reflect.New(reflect.TypeOf([]interface{}{})).Elem()
+		} else {
+			f.value = reflect.New(reflect.TypeOf((*interface{})(nil)).Elem()).Elem()
+			exact = append(exact, f)
+		}
+		fields = append(fields, f)
+	}
+
+	var done func() bool
+	if len(exact) == len(fields) {
+		done = func() bool {
+			for _, f := range exact {
+				if !f.set {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	if len(fields) > 0 {
+		if err := fill(NewDecoder(r), fields, done); err != nil && err != errScanDone {
+			return nil, err
+		}
+	}
+
+	values := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		if f.set {
+			values[f.name] = f.value.Interface()
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,62 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtract(t *testing.T) {
+
+	values, err := Extract(bytes.NewBufferString(unmarshalDoc),
+		JsonPath{"order", "id"},
+		JsonPath{"order", "shipping", "address"},
+		JsonPath{"order", "shipping", "address", "city"},
+		JsonPath{"order", "lines", 1, "qty"},
+		JsonPath{"order", "lines", AnyIndex, "sku"},
+		JsonPath{"order", "missing"},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"$.order.id":                    "A-1",
+		"$.order.shipping.address":      map[string]interface{}{"city": "Oslo", "zip": "0150"},
+		"$.order.shipping.address.city": "Oslo",
+		"$.order.lines[1].qty":          3.0,
+		"$.order.lines[*].sku":          []interface{}{"x1", "x2", "x3"},
+	}, values)
+}
+
+func TestExtractRoot(t *testing.T) {
+
+	values, err := Extract(bytes.NewBufferString(`[1,{"a":2}]`), JsonPath{}, JsonPath{1, "a"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"$":      []interface{}{1.0, map[string]interface{}{"a": 2.0}},
+		"$[1].a": 2.0,
+	}, values)
+}
+
+func TestExtractStopsEarly(t *testing.T) {
+
+	// the input is invalid after the values that are needed
+	in := `{"a":{"b":1},"c":[true,null], !`
+	values, err := Extract(bytes.NewBufferString(in), JsonPath{"c", 1}, JsonPath{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"$.a.b": 1.0, "$.c[1]": nil}, values)
+
+	// with AnyIndex, or a path that is not found, the whole input is read
+	_, err = Extract(bytes.NewBufferString(in), JsonPath{"a", "b"}, JsonPath{"c", AnyIndex})
+	assert.IsType(t, &json.SyntaxError{}, err)
+	_, err = Extract(bytes.NewBufferString(in), JsonPath{"a", "b"}, JsonPath{"d"})
+	assert.IsType(t, &json.SyntaxError{}, err)
+}
+
+func TestExtractNoPaths(t *testing.T) {
+
+	values, err := Extract(bytes.NewBufferString(`{`))
+	require.NoError(t, err)
+	assert.Empty(t, values)
+}
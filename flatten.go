@@ -0,0 +1,307 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Leaf is a value in the flat view of a JSON document produced by Flattener: a string, number, bool or nil, or an
+// empty object or array, which are represented as map[string]interface{}{} and []interface{}{}.
+type Leaf struct {
+	Key   string   // the path rendered by the Flattener's Format
+	Path  JsonPath // the path of the value
+	Value interface{}
+}
+
+// PathFormat renders a path as a string. FormatPath is a PathFormat.
+type PathFormat func(JsonPath) string
+
+// JoinPath returns a PathFormat that joins the keys and indices of a path with sep, as in a.b.0.c. The path of the
+// root is rendered as an empty string. Unlike FormatPath the result does not distinguish keys from indices, or keys
+// that contain sep, so it cannot always be parsed back to the path.
+func JoinPath(sep string) PathFormat {
+	return func(p JsonPath) string {
+		parts := make([]string, len(p))
+		for i, e := range p {
+			switch e := e.(type) {
+			case string:
+				parts[i] = e
+			case int:
+				parts[i] = strconv.Itoa(e)
+			}
+		}
+		return strings.Join(parts, sep)
+	}
+}
+
+// Flattener reads JSON values as a sequence of leaves, each a scalar value with its path.
+type Flattener struct {
+	// Format renders the Key of each Leaf. FormatPath is used if Format is nil.
+	Format PathFormat
+}
+
+// Flatten reads the next value from d and calls fn with each of its leaves in the order they appear. Paths are those
+// reported by the Decoder, so they are relative to the root of the stream. Numbers are float64 values, or
+// json.Number values if UseNumber has been called on d.
+//
+// Leaves are read one token at a time, so the value is never held in memory. If fn returns an error Flatten stops
+// and returns it.
+func (f *Flattener) Flatten(d *Decoder, fn func(Leaf) error) error {
+
+	format := f.Format
+	if format == nil {
+		format = FormatPath
+	}
+	emit := func(path JsonPath, v interface{}) error {
+		return fn(Leaf{Key: format(path), Path: path, Value: v})
+	}
+
+	depth := len(d.path)
+	for {
+		kind, err := d.next()
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case tokenObjectStart, tokenArrayStart:
+			if d.More() {
+				break
+			}
+			// an empty object or array is a leaf
+			path := d.path[:len(d.path)-1].jsonPath()
+			if _, err := d.next(); err != nil {
+				return err
+			}
+			var v interface{} = map[string]interface{}{}
+			if kind == tokenArrayStart {
+				v = []interface{}{}
+			}
+			if err := emit(path, v); err != nil {
+				return err
+			}
+		case tokenObjectEnd, tokenArrayEnd, tokenKey:
+		default:
+			v, err := d.scalar(kind)
+			if err != nil {
+				return err
+			}
+			if err := emit(d.path.jsonPath(), v); err != nil {
+				return err
+			}
+		}
+		if len(d.path) == depth {
+			return nil
+		}
+	}
+}
+
+// ConflictError is returned by Unflattener.Add when the path of a leaf is incompatible with the leaves added before
+// it.
+type ConflictError struct {
+	Msg  string
+	Path JsonPath // path of the leaf being added
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("jsonpath: %s at %v", e.Msg, e.Path)
+}
+
+// DefaultMaxIndex is the largest array index accepted by an Unflattener whose MaxIndex is zero, and by Ungron.
+const DefaultMaxIndex = 1<<20 - 1
+
+// Unflattener rebuilds a JSON document from its leaves, which may be added in any order. Object members are written
+// in the order their keys were first added. Array elements are written in index order, and elements for which no
+// leaf has been added are written as null.
+//
+// The zero value is an Unflattener with no leaves.
+type Unflattener struct {
+	// MaxIndex is the largest array index that may be added. Room is kept for every element of an array up to its
+	// largest index, so MaxIndex bounds the memory used by a single leaf. If it is zero, DefaultMaxIndex is used.
+	MaxIndex int
+
+	root flatNode
+}
+
+// flatNode is a value in the document being rebuilt.
+type flatNode struct {
+	kind    byte // 0 until the node is used, '{' for an object, '[' for an array, or 'v' for a leaf
+	value   interface{}
+	keys    []string // object keys in the order they were added
+	members map[string]*flatNode
+	elems   []*flatNode
+}
+
+// Add adds the leaf value at path. It returns a *ConflictError if a value has already been added at path, if an
+// object key or array index in path belongs to a leaf, if path uses a key where an index has been used before or
+// the reverse, or if an index in path exceeds MaxIndex. An empty object or array may be added as a leaf at the path of an existing object or array of the
+// same kind, and keys or indices may then be added within it.
+func (u *Unflattener) Add(path JsonPath, value interface{}) error {
+
+	conflict := func(msg string) error {
+		return &ConflictError{Msg: msg, Path: append(JsonPath{}, path...)}
+	}
+	max := u.MaxIndex
+	if max == 0 {
+		max = DefaultMaxIndex
+	}
+	n := &u.root
+	for i, e := range path {
+		var kind byte
+		switch e := e.(type) {
+		case string:
+			kind = '{'
+		case int:
+			if e < 0 {
+				return conflict("invalid array index " + strconv.Itoa(e))
+			}
+			if e > max {
+				return conflict(fmt.Sprintf("array index %d exceeds the maximum of %d", e, max))
+			}
+			kind = '['
+		default:
+			return conflict(fmt.Sprintf("invalid path element type %T", e))
+		}
+		switch n.kind {
+		case 0:
+			n.kind = kind
+		case 'v':
+			return conflict(fmt.Sprintf("%v is a leaf", path[:i]))
+		case kind:
+		default:
+			return conflict(fmt.Sprintf("%v is not an %s", path[:i], kindName(kind)))
+		}
+		n = n.child(e)
+	}
+
+	switch n.kind {
+	case 0:
+		n.setLeaf(value)
+		return nil
+	case '{', '[':
+		if isEmptyContainer(value) == n.kind {
+			return nil
+		}
+	}
+	return conflict("duplicate leaf")
+}
+
+// kindName returns the name of an object or array kind.
+func kindName(kind byte) string {
+	if kind == '{' {
+		return "object"
+	}
+	return "array"
+}
+
+// isEmptyContainer returns '{' or '[' if v is an empty object or array, and 0 otherwise.
+func isEmptyContainer(v interface{}) byte {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return '{'
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return '['
+		}
+	}
+	return 0
+}
+
+// setLeaf makes n the leaf value v, or an empty object or array.
+func (n *flatNode) setLeaf(v interface{}) {
+	if n.kind = isEmptyContainer(v); n.kind == 0 {
+		n.kind = 'v'
+		n.value = v
+	}
+}
+
+// child returns the member or element of n for the path element e, creating it if necessary.
+func (n *flatNode) child(e interface{}) *flatNode {
+	if key, ok := e.(string); ok {
+		c, ok := n.members[key]
+		if !ok {
+			if n.members == nil {
+				n.members = map[string]*flatNode{}
+			}
+			c = &flatNode{}
+			n.members[key] = c
+			n.keys = append(n.keys, key)
+		}
+		return c
+	}
+	i := e.(int)
+	for len(n.elems) <= i {
+		n.elems = append(n.elems, nil)
+	}
+	if n.elems[i] == nil {
+		n.elems[i] = &flatNode{}
+	}
+	return n.elems[i]
+}
+
+// Value returns the document as a tree of map[string]interface{}, []interface{} and leaf values, or nil if no leaves
+// have been added.
+func (u *Unflattener) Value() interface{} {
+	return u.root.tree()
+}
+
+func (n *flatNode) tree() interface{} {
+	if n == nil {
+		return nil
+	}
+	switch n.kind {
+	case '{':
+		m := make(map[string]interface{}, len(n.keys))
+		for _, k := range n.keys {
+			m[k] = n.members[k].tree()
+		}
+		return m
+	case '[':
+		a := make([]interface{}, len(n.elems))
+		for i, c := range n.elems {
+			a[i] = c.tree()
+		}
+		return a
+	}
+	return n.value
+}
+
+// Encode writes the document to e, or null if no leaves have been added.
+func (u *Unflattener) Encode(e *Encoder) error {
+	return u.root.encode(e)
+}
+
+func (n *flatNode) encode(e *Encoder) error {
+	if n == nil {
+		return e.WriteValue(nil)
+	}
+	switch n.kind {
+	case '{':
+		if err := e.WriteToken(json.Delim('{')); err != nil {
+			return err
+		}
+		for _, k := range n.keys {
+			if err := e.WriteKey(k); err != nil {
+				return err
+			}
+			if err := n.members[k].encode(e); err != nil {
+				return err
+			}
+		}
+		return e.WriteToken(json.Delim('}'))
+	case '[':
+		if err := e.WriteToken(json.Delim('[')); err != nil {
+			return err
+		}
+		for _, c := range n.elems {
+			if err := c.encode(e); err != nil {
+				return err
+			}
+		}
+		return e.WriteToken(json.Delim(']'))
+	}
+	return e.WriteValue(n.value)
+}
@@ -0,0 +1,147 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func flatten(t *testing.T, f *Flattener, in string) []Leaf {
+	var leaves []Leaf
+	d := NewDecoder(bytes.NewBufferString(in))
+	require.NoError(t, f.Flatten(d, func(l Leaf) error {
+		leaves = append(leaves, l)
+		return nil
+	}))
+	return leaves
+}
+
+func TestFlatten(t *testing.T) {
+
+	leaves := flatten(t, &Flattener{}, `{"a":{"b":[1,"x",{"c":null}],"d.e":true},"f":{},"g":[]}`)
+	assert.Equal(t, []Leaf{
+		{"$.a.b[0]", JsonPath{"a", "b", 0}, 1.0},
+		{"$.a.b[1]", JsonPath{"a", "b", 1}, "x"},
+		{"$.a.b[2].c", JsonPath{"a", "b", 2, "c"}, nil},
+		{`$.a["d.e"]`, JsonPath{"a", "d.e"}, true},
+		{"$.f", JsonPath{"f"}, map[string]interface{}{}},
+		{"$.g", JsonPath{"g"}, []interface{}{}},
+	}, leaves)
+
+	leaves = flatten(t, &Flattener{Format: JoinPath("/")}, `[[1],{"k":false}]`)
+	assert.Equal(t, []Leaf{
+		{"0/0", JsonPath{0, 0}, 1.0},
+		{"1/k", JsonPath{1, "k"}, false},
+	}, leaves)
+
+	assert.Equal(t, []Leaf{{"$", JsonPath{}, "s"}}, flatten(t, &Flattener{}, `"s"`))
+	assert.Equal(t, []Leaf{{"", JsonPath{}, []interface{}{}}}, flatten(t, &Flattener{Format: JoinPath(".")}, `[]`))
+}
+
+func TestFlattenStream(t *testing.T) {
+
+	d := NewDecoder(bytes.NewBufferString(`{"a":1} [2] 3`))
+	d.UseNumber()
+	var values []interface{}
+	f := &Flattener{}
+	for d.More() {
+		require.NoError(t, f.Flatten(d, func(l Leaf) error {
+			values = append(values, l.Value)
+			return nil
+		}))
+	}
+	assert.Equal(t, []interface{}{json.Number("1"), json.Number("2"), json.Number("3")}, values)
+}
+
+func TestFlattenErrors(t *testing.T) {
+
+	stop := errors.New("stop")
+	d := NewDecoder(bytes.NewBufferString(`[1,2]`))
+	n := 0
+	err := (&Flattener{}).Flatten(d, func(Leaf) error {
+		n++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, n)
+
+	d = NewDecoder(bytes.NewBufferString(`{"a":[1,}`))
+	err = (&Flattener{}).Flatten(d, func(Leaf) error { return nil })
+	assert.IsType(t, &json.SyntaxError{}, err)
+}
+
+func TestUnflatten(t *testing.T) {
+
+	in := `{"a":{"b":[1,"x",{"c":null}],"d.e":true},"f":{},"g":[],"h":[{},[]]}`
+	leaves := flatten(t, &Flattener{}, in)
+
+	// add the leaves in reverse order
+	var u Unflattener
+	for i := len(leaves) - 1; i >= 0; i-- {
+		require.NoError(t, u.Add(leaves[i].Path, leaves[i].Value))
+	}
+	var expected interface{}
+	require.NoError(t, json.Unmarshal([]byte(in), &expected))
+	assert.Equal(t, expected, u.Value())
+
+	var out bytes.Buffer
+	require.NoError(t, u.Encode(NewEncoder(&out)))
+	assert.Equal(t, `{"h":[{},[]],"g":[],"f":{},"a":{"d.e":true,"b":[1,"x",{"c":null}]}}`+"\n", out.String())
+}
+
+func TestUnflattenGaps(t *testing.T) {
+
+	var u Unflattener
+	require.NoError(t, u.Add(JsonPath{2}, "c"))
+	require.NoError(t, u.Add(JsonPath{0, "a"}, 1))
+	var out bytes.Buffer
+	require.NoError(t, u.Encode(NewEncoder(&out)))
+	assert.Equal(t, `[{"a":1},null,"c"]`+"\n", out.String())
+
+	var empty Unflattener
+	assert.Nil(t, empty.Value())
+	out.Reset()
+	require.NoError(t, empty.Encode(NewEncoder(&out)))
+	assert.Equal(t, "null\n", out.String())
+}
+
+func TestUnflattenConflicts(t *testing.T) {
+
+	tests := []struct {
+		first  JsonPath
+		second JsonPath
+	}{
+		{JsonPath{"a"}, JsonPath{"a"}},
+		{JsonPath{"a"}, JsonPath{"a", "b"}},
+		{JsonPath{"a", "b"}, JsonPath{"a"}},
+		{JsonPath{"a", "b"}, JsonPath{"a", 0}},
+		{JsonPath{"a", 0}, JsonPath{"a", "b"}},
+		{JsonPath{"a"}, JsonPath{0}},
+		{JsonPath{}, JsonPath{"a"}},
+		{JsonPath{"a"}, JsonPath{"b", AnyIndex}},
+	}
+	for _, tst := range tests {
+		var u Unflattener
+		require.NoError(t, u.Add(tst.first, 1))
+		err := u.Add(tst.second, 2)
+		assert.IsType(t, &ConflictError{}, err, "%v then %v", tst.first, tst.second)
+	}
+
+	// an empty object is compatible with the members of the same object
+	var u Unflattener
+	require.NoError(t, u.Add(JsonPath{"a", "b"}, 1))
+	require.NoError(t, u.Add(JsonPath{"a"}, map[string]interface{}{}))
+	assert.IsType(t, &ConflictError{}, u.Add(JsonPath{"a"}, []interface{}{}))
+
+	// indices are bounded by MaxIndex
+	u = Unflattener{}
+	assert.IsType(t, &ConflictError{}, u.Add(JsonPath{"a", 999999999}, 1))
+	assert.NoError(t, u.Add(JsonPath{"a", DefaultMaxIndex}, 1))
+	u = Unflattener{MaxIndex: 2}
+	require.NoError(t, u.Add(JsonPath{2}, 1))
+	assert.EqualError(t, u.Add(JsonPath{3}, 1), "jsonpath: array index 3 exceeds the maximum of 2 at [3]")
+}
@@ -0,0 +1,341 @@
+package jsonpath
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// gronRoot is the name given to the root of the document in gron output.
+const gronRoot = "json"
+
+// Gron reads the next value from d and writes it to w as lines of JavaScript assignments in the format of the gron
+// tool, one for each value in the document, in the order they appear:
+//
+//	json = {};
+//	json.a = [];
+//	json.a[0] = "x";
+//	json["b.c"] = true;
+//
+// Each object and array is assigned {} or [] before its contents, and scalars are written as they appear in the
+// input, so the lines can be searched with grep and converted back with Ungron. Keys that are not made up of letters,
+// digits and underscores are written as JSON strings in brackets.
+func Gron(d *Decoder, w io.Writer) error {
+
+	bw := bufio.NewWriter(w)
+	var line []byte
+	depth := len(d.path)
+	for {
+		kind, err := d.next()
+		if err != nil {
+			return err
+		}
+		var value []byte
+		path := d.path
+		switch kind {
+		case tokenObjectStart:
+			value, path = []byte("{}"), d.path[:len(d.path)-1]
+		case tokenArrayStart:
+			value, path = []byte("[]"), d.path[:len(d.path)-1]
+		case tokenTrue:
+			value = literalTrue
+		case tokenFalse:
+			value = literalFalse
+		case tokenNull:
+			value = literalNull
+		case tokenString, tokenNumber:
+			value = d.tok.raw
+		}
+
+		if value != nil {
+			line = appendGronPath(append(line[:0], gronRoot...), path)
+			line = append(line, " = "...)
+			line = append(line, value...)
+			line = append(line, ";\n"...)
+			if _, err := bw.Write(line); err != nil {
+				return err
+			}
+		}
+		if len(d.path) == depth {
+			return bw.Flush()
+		}
+	}
+}
+
+// appendGronPath appends the path to b in the form used by gron.
+func appendGronPath(b []byte, path pathStack) []byte {
+	for i := range path {
+		f := &path[i]
+		switch {
+		case f.array:
+			b = append(b, '[')
+			b = strconv.AppendInt(b, int64(f.index), 10)
+			b = append(b, ']')
+		case isIdentifier(string(f.key)):
+			b = append(b, '.')
+			b = append(b, f.key...)
+		default:
+			key, _ := json.Marshal(string(f.key))
+			b = append(b, '[')
+			b = append(b, key...)
+			b = append(b, ']')
+		}
+	}
+	return b
+}
+
+// Ungron reads lines in the format written by Gron from r and writes the JSON they describe to e. The lines may have
+// been filtered, for example with grep: objects and arrays are created for the paths of the remaining lines even if
+// their {} or [] assignments have been removed, and array elements that are missing before the last element present
+// are written as null. Each assignment to the root, such as json = {}, begins a new top-level value.
+//
+// The document is written as the lines are read, so the lines must be in the order Gron writes them: the lines
+// within an object or array must be contiguous, and the elements of an array must be in increasing index order. A
+// key that appears again after the lines of another key of its object is an error, rather than a duplicate key.
+// Blank lines are ignored. Indices above DefaultMaxIndex are rejected, so that a single line cannot require a run of
+// nulls of any length.
+func Ungron(r io.Reader, e *Encoder) error {
+
+	u := ungron{e: e}
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<30)
+	for n := 1; s.Scan(); n++ {
+		line := bytes.TrimSpace(s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		path, value, err := parseGronLine(line)
+		if err == nil {
+			err = u.assign(path, value)
+		}
+		if err != nil {
+			return fmt.Errorf("jsonpath: gron line %d: %v", n, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	return u.closeTo(0)
+}
+
+// ungron holds the state of Ungron: the objects and arrays that are open in the output.
+type ungron struct {
+	e     *Encoder
+	kinds []byte            // the kinds of the open objects and arrays, '{' or '['
+	keys  []map[string]bool // the keys written in each open object, or nil for an array
+	path  JsonPath          // the path of the innermost open object or array
+}
+
+// assign writes the value at path, opening and closing objects and arrays as required.
+func (u *ungron) assign(path JsonPath, value json.RawMessage) error {
+
+	if len(path) == 0 {
+		// a new top-level value
+		if err := u.closeTo(0); err != nil {
+			return err
+		}
+		if c := containerKind(value); c != 0 {
+			return u.open(c, path)
+		}
+		return u.e.writeRaw(value)
+	}
+
+	if len(u.kinds) == 0 {
+		if err := u.open(elementKind(path[0]), JsonPath{}); err != nil {
+			return err
+		}
+	}
+
+	// close the objects and arrays that do not enclose the value
+	parent := path[:len(path)-1]
+	common := 0
+	for common < len(u.path) && common < len(parent) && u.path[common] == parent[common] {
+		common++
+	}
+	if err := u.closeTo(common + 1); err != nil {
+		return err
+	}
+
+	// open the objects and arrays that enclose the value but are not yet open
+	for i := common; i < len(parent); i++ {
+		if err := u.position(parent[i]); err != nil {
+			return err
+		}
+		if err := u.open(elementKind(path[i+1]), path[:i+1]); err != nil {
+			return err
+		}
+	}
+
+	if err := u.position(path[len(path)-1]); err != nil {
+		return err
+	}
+	if c := containerKind(value); c != 0 {
+		return u.open(c, path)
+	}
+	return u.e.writeRaw(value)
+}
+
+// elementKind returns the kind of container that the path element e belongs to.
+func elementKind(e interface{}) byte {
+	if _, ok := e.(int); ok {
+		return '['
+	}
+	return '{'
+}
+
+// containerKind returns '{' or '[' if the value is an empty object or array, and 0 otherwise.
+func containerKind(value json.RawMessage) byte {
+	switch string(value) {
+	case "{}":
+		return '{'
+	case "[]":
+		return '['
+	}
+	return 0
+}
+
+// open writes the start of an object or array at path.
+func (u *ungron) open(kind byte, path JsonPath) error {
+	u.kinds = append(u.kinds, kind)
+	var keys map[string]bool
+	if kind == '{' {
+		keys = map[string]bool{}
+	}
+	u.keys = append(u.keys, keys)
+	u.path = append(u.path[:0], path...)
+	return u.e.WriteToken(json.Delim(kind))
+}
+
+// closeTo closes open objects and arrays until depth remain open.
+func (u *ungron) closeTo(depth int) error {
+	for len(u.kinds) > depth {
+		end := json.Delim('}')
+		if u.kinds[len(u.kinds)-1] == '[' {
+			end = ']'
+		}
+		u.kinds = u.kinds[:len(u.kinds)-1]
+		u.keys = u.keys[:len(u.kinds)]
+		if len(u.kinds) > 0 {
+			u.path = u.path[:len(u.kinds)-1]
+		}
+		if err := u.e.WriteToken(end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// position writes the key of the path element e, or the nulls that precede its index, in the innermost open object
+// or array.
+func (u *ungron) position(e interface{}) error {
+	kind := u.kinds[len(u.kinds)-1]
+	if kind != elementKind(e) {
+		return fmt.Errorf("%s used in an %s", gronElement(e), kindName(kind))
+	}
+	key, ok := e.(string)
+	if ok {
+		// the lines of a key must be contiguous, since a key that reappears cannot be merged with its earlier lines
+		keys := u.keys[len(u.keys)-1]
+		if keys[key] {
+			return fmt.Errorf("%s repeated in an object", gronElement(e))
+		}
+		keys[key] = true
+		return u.e.WriteKey(key)
+	}
+	index := e.(int)
+	if index > DefaultMaxIndex {
+		return fmt.Errorf("index %d exceeds the maximum of %d", index, DefaultMaxIndex)
+	}
+	if index <= u.e.path.top().index {
+		return fmt.Errorf("index %d is out of order", index)
+	}
+	for u.e.path.top().index+1 < index {
+		if err := u.e.WriteValue(nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gronElement describes a path element in an error message.
+func gronElement(e interface{}) string {
+	if key, ok := e.(string); ok {
+		return fmt.Sprintf("key %q", key)
+	}
+	return fmt.Sprintf("index %d", e)
+}
+
+// parseGronLine parses a line of the form path = value; written by Gron.
+func parseGronLine(line []byte) (JsonPath, json.RawMessage, error) {
+
+	i := 0
+	for i < len(line) && (line[i] == '_' || line[i] == '$' || isAlnum(line[i])) {
+		i++
+	}
+	if i == 0 {
+		return nil, nil, fmt.Errorf("expected a name at the start of the line")
+	}
+
+	path := JsonPath{}
+	for i < len(line) && (line[i] == '.' || line[i] == '[') {
+		if line[i] == '.' {
+			j := i + 1
+			for j < len(line) && (line[j] == '_' || line[j] == '$' || isAlnum(line[j])) {
+				j++
+			}
+			if j == i+1 {
+				return nil, nil, fmt.Errorf("expected a key at offset %d", i+1)
+			}
+			path = append(path, string(line[i+1:j]))
+			i = j
+			continue
+		}
+
+		// an index or a quoted key in brackets
+		j := i + 1
+		if j < len(line) && line[j] == '"' {
+			for j++; j < len(line) && line[j] != '"'; j++ {
+				if line[j] == '\\' {
+					j++
+				}
+			}
+			var key string
+			if j >= len(line) || json.Unmarshal(line[i+1:j+1], &key) != nil {
+				return nil, nil, fmt.Errorf("invalid key at offset %d", i+1)
+			}
+			path = append(path, key)
+			j++
+		} else {
+			for j < len(line) && line[j] >= '0' && line[j] <= '9' {
+				j++
+			}
+			index, err := strconv.Atoi(string(line[i+1 : j]))
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid index at offset %d", i+1)
+			}
+			path = append(path, index)
+		}
+		if j >= len(line) || line[j] != ']' {
+			return nil, nil, fmt.Errorf("expected ] at offset %d", j)
+		}
+		i = j + 1
+	}
+
+	rest := bytes.TrimSpace(line[i:])
+	if len(rest) == 0 || rest[0] != '=' {
+		return nil, nil, fmt.Errorf("expected = at offset %d", i)
+	}
+	value := bytes.TrimSpace(bytes.TrimSuffix(bytes.TrimSpace(rest[1:]), []byte(";")))
+	if !json.Valid(value) {
+		return nil, nil, fmt.Errorf("invalid value %s", value)
+	}
+	return path, value, nil
+}
+
+// isAlnum reports whether c is an ASCII letter or digit.
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
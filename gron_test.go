@@ -0,0 +1,109 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gronDoc = `{"a":{"b":[1,"x\n",{"c":null}],"d.e":true},"f":{},"g":[],"h i":1.50}`
+
+const gronLines = `json = {};
+json.a = {};
+json.a.b = [];
+json.a.b[0] = 1;
+json.a.b[1] = "x\n";
+json.a.b[2] = {};
+json.a.b[2].c = null;
+json.a["d.e"] = true;
+json.f = {};
+json.g = [];
+json["h i"] = 1.50;
+`
+
+func TestGron(t *testing.T) {
+
+	var out bytes.Buffer
+	require.NoError(t, Gron(NewDecoder(strings.NewReader(gronDoc)), &out))
+	assert.Equal(t, gronLines, out.String())
+
+	out.Reset()
+	d := NewDecoder(strings.NewReader(`"s" [false]`))
+	for d.More() {
+		require.NoError(t, Gron(d, &out))
+	}
+	assert.Equal(t, "json = \"s\";\njson = [];\njson[0] = false;\n", out.String())
+}
+
+func TestGronSyntaxError(t *testing.T) {
+
+	var out bytes.Buffer
+	err := Gron(NewDecoder(strings.NewReader(`{"a":[1,}`)), &out)
+	assert.IsType(t, &json.SyntaxError{}, err)
+}
+
+func ungronString(t *testing.T, in string) string {
+	var out bytes.Buffer
+	require.NoError(t, Ungron(strings.NewReader(in), NewEncoder(&out)))
+	return out.String()
+}
+
+func TestUngron(t *testing.T) {
+
+	assert.Equal(t, gronDoc+"\n", ungronString(t, gronLines))
+
+	// filtered lines
+	assert.Equal(t, `{"a":{"b":[null,"x\n",{"c":null}]}}`+"\n", ungronString(t, `
+json.a.b[1] = "x\n";
+json.a.b[2].c = null;
+`))
+	assert.Equal(t, `[null,[{"k":1}]]`+"\n", ungronString(t, `json[1][0].k = 1;`))
+
+	// several top-level values
+	assert.Equal(t, "1\n{\"a\":2}\n[]\n", ungronString(t, "json = 1;\njson = {};\njson.a = 2;\njson = [];\n"))
+
+	// the root may have any name and spacing may vary
+	assert.Equal(t, `{"a":"x = y;"}`+"\n", ungronString(t, `doc.a="x = y;"`))
+}
+
+func TestUngronErrors(t *testing.T) {
+
+	for _, in := range []string{
+		`= 1;`,
+		`json. = 1;`,
+		`json[x] = 1;`,
+		`json["a] = 1;`,
+		`json[1 = 1;`,
+		`json.a 1;`,
+		`json.a = {;`,
+		"json.a = 1;\njson[0] = 2;",
+		"json[1] = 1;\njson[0] = 2;",
+		"json.a[0] = 1;\njson.a.b = 2;",
+		"json[999999999] = 1;",
+		"json.a = 1;\njson.a = 2;",
+		"json.a.x = 1;\njson.b = 2;\njson.a.y = 3;",
+		"json[0].a.x = 1;\njson[0].b = 2;\njson[0].a = {};",
+	} {
+		var out bytes.Buffer
+		err := Ungron(strings.NewReader(in), NewEncoder(&out))
+		assert.Error(t, err, in)
+	}
+
+	var out bytes.Buffer
+	err := Ungron(strings.NewReader("json.a.x = 1;\njson.b = 2;\njson.a.y = 3;"), NewEncoder(&out))
+	assert.EqualError(t, err, `jsonpath: gron line 3: key "a" repeated in an object`)
+}
+
+func TestGronRoundTrip(t *testing.T) {
+
+	in := `{"x":[[1,2],[],[{"y":"é<>"}]],"":{"\"":0}}`
+	var lines bytes.Buffer
+	require.NoError(t, Gron(NewDecoder(strings.NewReader(in)), &lines))
+	var out bytes.Buffer
+	require.NoError(t, Ungron(&lines, NewEncoder(&out)))
+	assert.Equal(t, in+"\n", out.String())
+}
@@ -0,0 +1,38 @@
+package jsonpath
+
+import (
+	"crypto/sha256"
+	"hash"
+)
+
+// ValueHash is the digest of a value, computed by the action returned by HashAction.
+type ValueHash struct {
+	Path JsonPath // the path of the value, from the root of the stream being scanned
+	Sum  []byte   // the digest of the value's canonical form
+}
+
+// HashAction returns a DecodeAction that reads the value at the Decoder's position and calls fn with its path and
+// the digest of its RFC 8785 canonical form, as written by Canonicalize. Equal values have equal digests however they
+// are formatted and whatever the order of their object keys, so the action can be added to PathActions to find
+// duplicate values while scanning, for example each element of an array:
+//
+//	actions.Add(HashAction(nil, func(h ValueHash) error {
+//		seen[hex.EncodeToString(h.Sum)] = append(seen[hex.EncodeToString(h.Sum)], h.Path)
+//		return nil
+//	}), "items", AnyIndex)
+//
+// The value is hashed with a hash.Hash created by newHash for each value, or with SHA-256 if newHash is nil. The
+// members of each object in the value are held in memory while they are sorted. If fn returns an error, the scan
+// stops and returns it.
+func HashAction(newHash func() hash.Hash, fn func(ValueHash) error) DecodeAction {
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	return func(d *Decoder) error {
+		h := newHash()
+		if err := Canonicalize(d, h); err != nil {
+			return err
+		}
+		return fn(ValueHash{Path: d.Path(), Sum: h.Sum(nil)})
+	}
+}
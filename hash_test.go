@@ -0,0 +1,60 @@
+package jsonpath
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashAction(t *testing.T) {
+
+	const doc = `{"items":[
+		{"id":1,"tags":["a","b"]},
+		{ "tags" : [ "a", "b" ], "id" : 1.0 },
+		{"id":2,"tags":["b","a"]}
+	],"other":{"id":1,"tags":["a","b"]}}`
+
+	var hashes []ValueHash
+	collect := func(h ValueHash) error {
+		hashes = append(hashes, h)
+		return nil
+	}
+	actions := &PathActions{}
+	actions.Add(HashAction(nil, collect), "items", AnyIndex)
+	actions.Add(HashAction(md5.New, collect), "other")
+	_, err := NewDecoder(strings.NewReader(doc)).Scan(actions)
+	require.NoError(t, err)
+
+	require.Len(t, hashes, 4)
+	assert.Equal(t, JsonPath{"items", 0}, hashes[0].Path)
+	assert.Equal(t, JsonPath{"items", 1}, hashes[1].Path)
+	assert.Equal(t, JsonPath{"items", 2}, hashes[2].Path)
+	assert.Equal(t, JsonPath{"other"}, hashes[3].Path)
+
+	sum := sha256.Sum256([]byte(`{"id":1,"tags":["a","b"]}`))
+	assert.Equal(t, hex.EncodeToString(sum[:]), hex.EncodeToString(hashes[0].Sum))
+	assert.Equal(t, hashes[0].Sum, hashes[1].Sum)
+	assert.NotEqual(t, hashes[0].Sum, hashes[2].Sum)
+	md := md5.Sum([]byte(`{"id":1,"tags":["a","b"]}`))
+	assert.Equal(t, md[:], hashes[3].Sum)
+}
+
+func TestHashActionError(t *testing.T) {
+
+	stop := errors.New("stop")
+	actions := &PathActions{}
+	actions.Add(HashAction(nil, func(ValueHash) error { return stop }), AnyIndex)
+	_, err := NewDecoder(strings.NewReader(`[1,2]`)).Scan(actions)
+	assert.Equal(t, stop, err)
+
+	actions = &PathActions{}
+	actions.Add(HashAction(nil, func(ValueHash) error { return nil }), "a")
+	_, err = NewDecoder(strings.NewReader(`{"a":{"b":1,"b":2}}`)).Scan(actions)
+	assert.Error(t, err)
+}
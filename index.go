@@ -0,0 +1,267 @@
+package jsonpath
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// indexMagic begins every index written by an Indexer, followed by the format version.
+var indexMagic = []byte("JPIX\x01")
+
+// Indexer records the input offsets of the values at chosen paths of a document in a compact index, so that an
+// IndexedReader can later start decoding at any of them without reading the document from the beginning.
+//
+// Values are chosen with Add, by path, and with Depth, by their depth in the document. An Indexer can be used many
+// times, but Add must not be called concurrently with Index.
+type Indexer struct {
+	// Depth, if positive, causes every value whose path has at most Depth elements to be indexed in addition to the
+	// values at the added paths. A Depth of 1 indexes each member of the top-level object or element of the
+	// top-level array.
+	Depth int
+
+	paths []JsonPath
+}
+
+// Add specifies that the values at path are to be indexed. AnyIndex may be used in the path to match any array
+// index.
+func (x *Indexer) Add(path ...interface{}) {
+	x.paths = append(x.paths, path)
+}
+
+// Index reads the next value from d and writes an index of the offsets of the chosen values within it to w. The
+// offsets are those reported by d.InputOffset, so d must read the document from its beginning for the index to be
+// used with an IndexedReader.
+//
+// The index records each path and offset in document order, with each path stored as the number of elements it
+// shares with the path before it followed by the rest of its elements, and each offset as the difference from the
+// offset before it. Only the objects and arrays that contain chosen values are read token by token; others are
+// skipped.
+func (x *Indexer) Index(d *Decoder, w io.Writer) error {
+	iw := &indexWriter{w: bufio.NewWriter(w)}
+	iw.w.Write(indexMagic)
+	if err := x.value(d, iw); err != nil {
+		return err
+	}
+	return iw.w.Flush()
+}
+
+// value indexes the next value of d and the values within it.
+func (x *Indexer) value(d *Decoder, iw *indexWriter) error {
+
+	c, err := d.peekValue()
+	if err != nil {
+		return err
+	}
+	offset := d.InputOffset()
+
+	// an array element is counted when it is read, so the path of the next element is formed by counting it early
+	inArray := d.context == arrValue
+	if inArray {
+		d.path.incTop()
+	}
+	index, descend := x.wanted(d.path)
+	if index {
+		iw.add(d.path, offset)
+	}
+	if inArray {
+		d.path.top().index--
+	}
+
+	if !descend || (c != '{' && c != '[') {
+		_, err := d.skip(false)
+		return err
+	}
+	if _, err := d.next(); err != nil {
+		return err
+	}
+	for d.More() {
+		if c == '{' {
+			if _, err := d.next(); err != nil {
+				return err
+			}
+		}
+		if err := x.value(d, iw); err != nil {
+			return err
+		}
+	}
+	_, err = d.next()
+	return err
+}
+
+// wanted reports whether the value at s is to be indexed, and whether values within it may be.
+func (x *Indexer) wanted(s pathStack) (index, descend bool) {
+	index = len(s) <= x.Depth
+	descend = len(s) < x.Depth
+	for _, p := range x.paths {
+		if len(p) >= len(s) && matchesPrefix(s, p) {
+			index = index || len(p) == len(s)
+			descend = descend || len(p) > len(s)
+		}
+	}
+	return index, descend
+}
+
+// indexWriter writes the entries of an index.
+type indexWriter struct {
+	w      *bufio.Writer
+	path   JsonPath
+	offset int64
+	buf    []byte
+}
+
+// add writes the entry for the value at s.
+func (iw *indexWriter) add(s pathStack, offset int64) {
+	common := 0
+	for common < len(iw.path) && common < len(s) && s[common].matches(iw.path[common]) {
+		common++
+	}
+	b := appendUvarint(iw.buf[:0], uint64(common))
+	b = appendUvarint(b, uint64(len(s)-common))
+	for i := common; i < len(s); i++ {
+		if s[i].array {
+			b = appendUvarint(b, uint64(s[i].index)<<1|1)
+		} else {
+			b = appendUvarint(b, uint64(len(s[i].key))<<1)
+			b = append(b, s[i].key...)
+		}
+	}
+	b = appendUvarint(b, uint64(offset-iw.offset))
+	iw.w.Write(b)
+	iw.buf, iw.path, iw.offset = b, s.jsonPath(), offset
+}
+
+// appendUvarint appends the varint encoding of v to b.
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+// ErrInvalidIndex is returned by NewIndexedReader when the index was not written by an Indexer.
+var ErrInvalidIndex = errors.New("jsonpath: invalid index")
+
+// IndexedReader reads the values of a document at random, using an index written by an Indexer to start decoding
+// at the offset of a value rather than at the beginning of the document. An IndexedReader can be used concurrently
+// if its io.ReaderAt can.
+type IndexedReader struct {
+	r       io.ReaderAt
+	size    int64
+	offsets map[string]int64 // keyed by FormatPath
+}
+
+// NewIndexedReader returns an IndexedReader for the document of size bytes read from r, such as an *os.File, using
+// the index read from index. The index is held in memory.
+func NewIndexedReader(r io.ReaderAt, size int64, index io.Reader) (*IndexedReader, error) {
+
+	br := bufio.NewReader(index)
+	magic := make([]byte, len(indexMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || !bytes.Equal(magic, indexMagic) {
+		return nil, ErrInvalidIndex
+	}
+
+	x := &IndexedReader{r: r, size: size, offsets: map[string]int64{}}
+	var path JsonPath
+	var offset int64
+	for {
+		common, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return x, nil
+		}
+		n, err2 := binary.ReadUvarint(br)
+		if err != nil || err2 != nil || common > uint64(len(path)) || n > uint64(size) {
+			return nil, ErrInvalidIndex
+		}
+		path = path[:common]
+		for i := uint64(0); i < n; i++ {
+			e, err := binary.ReadUvarint(br)
+			if err != nil || e>>1 > uint64(size) {
+				return nil, ErrInvalidIndex
+			}
+			if e&1 == 1 {
+				path = append(path, int(e>>1))
+				continue
+			}
+			key := make([]byte, e>>1)
+			if _, err := io.ReadFull(br, key); err != nil {
+				return nil, ErrInvalidIndex
+			}
+			path = append(path, string(key))
+		}
+		delta, err := binary.ReadUvarint(br)
+		if err != nil || delta > uint64(size-offset) {
+			return nil, ErrInvalidIndex
+		}
+		offset += int64(delta)
+		x.offsets[FormatPath(path)] = offset
+	}
+}
+
+// Offset returns the offset of the value at path, and whether it is in the index.
+func (x *IndexedReader) Offset(path ...interface{}) (int64, bool) {
+	offset, ok := x.offsets[FormatPath(path)]
+	return offset, ok
+}
+
+// DecoderAt returns a Decoder positioned before the value at path, so that Decode, Token, Scan and the other methods
+// of the Decoder read the value. The Decoder starts reading at the offset of the value if it is in the index, or
+// otherwise at the offset of its nearest enclosing value in the index, from which it seeks forward to the path. The
+// Decoder's paths are those of the whole document, and after the value it continues with the rest of the document.
+//
+// DecoderAt returns false if the path is not in the document.
+func (x *IndexedReader) DecoderAt(path ...interface{}) (*Decoder, bool, error) {
+
+	n, offset := len(path), int64(0)
+	for ; n > 0; n-- {
+		if o, ok := x.offsets[FormatPath(path[:n])]; ok {
+			offset = o
+			break
+		}
+	}
+	if n == 0 {
+		// the offset of the root if it is indexed, and otherwise the beginning of the document
+		offset = x.offsets[FormatPath(JsonPath{})]
+	}
+
+	d := NewDecoder(io.NewSectionReader(x.r, offset, x.size-offset))
+	d.resumeAt(path[:n], offset)
+	if n == len(path) {
+		return d, true, nil
+	}
+	found, err := d.SeekTo(append([]interface{}(nil), path...)...)
+	if err != nil || !found {
+		return nil, false, err
+	}
+	return d, true, nil
+}
+
+// resumeAt prepares d, whose input begins at the given offset of a document with the value at path, to read the
+// value as if the document had been read up to it.
+func (d *Decoder) resumeAt(path JsonPath, offset int64) {
+
+	d.path.set(path)
+	d.tok.base = offset
+	d.tok.stack = d.tok.stack[:0]
+	for i := range path {
+		switch {
+		case i == 0:
+			d.tok.stack = append(d.tok.stack, stateTopValue)
+		case isIndex(path[i-1]):
+			d.tok.stack = append(d.tok.stack, stateArrayValue)
+		default:
+			d.tok.stack = append(d.tok.stack, stateObjectValue)
+		}
+	}
+
+	switch {
+	case len(path) == 0:
+		d.context, d.tok.state = none, stateTopValue
+	case isIndex(path[len(path)-1]):
+		// the element is counted when it is read
+		d.path.top().index--
+		d.context, d.tok.state = arrValue, stateArrayValue
+	default:
+		d.context, d.tok.state = objValue, stateObjectValue
+	}
+}
@@ -0,0 +1,147 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const indexDoc = `{
+  "meta": {"count": 3, "tags": ["a", "b"]},
+  "items": [
+    {"id": 1, "name": "one"},
+    {"id": 2, "name": "two", "parts": [{"sku": "x"}]},
+    {"id": 3, "name": "three"}
+  ],
+  "end": true
+}`
+
+func indexString(t *testing.T, x *Indexer, doc string) *IndexedReader {
+	var index bytes.Buffer
+	require.NoError(t, x.Index(NewDecoder(strings.NewReader(doc)), &index))
+	r, err := NewIndexedReader(strings.NewReader(doc), int64(len(doc)), &index)
+	require.NoError(t, err)
+	return r
+}
+
+func TestIndexOffsets(t *testing.T) {
+
+	x := &Indexer{}
+	x.Add("items", AnyIndex)
+	x.Add("meta", "tags")
+	r := indexString(t, x, indexDoc)
+
+	for _, tst := range []struct {
+		path   []interface{}
+		prefix string
+	}{
+		{[]interface{}{"items", 0}, `{"id": 1`},
+		{[]interface{}{"items", 1}, `{"id": 2`},
+		{[]interface{}{"items", 2}, `{"id": 3`},
+		{[]interface{}{"meta", "tags"}, `["a"`},
+	} {
+		offset, ok := r.Offset(tst.path...)
+		require.True(t, ok, "%v", tst.path)
+		assert.True(t, strings.HasPrefix(indexDoc[offset:], tst.prefix), "%v", tst.path)
+	}
+	_, ok := r.Offset("meta")
+	assert.False(t, ok)
+	_, ok = r.Offset("items", 1, "id")
+	assert.False(t, ok)
+
+	// Depth indexes every value down to a depth
+	r = indexString(t, &Indexer{Depth: 1}, indexDoc)
+	for _, p := range []JsonPath{{}, {"meta"}, {"items"}, {"end"}} {
+		_, ok := r.Offset(p...)
+		assert.True(t, ok, "%v", p)
+	}
+	_, ok = r.Offset("items", 0)
+	assert.False(t, ok)
+}
+
+func TestIndexedReaderDecoderAt(t *testing.T) {
+
+	x := &Indexer{}
+	x.Add("items", AnyIndex)
+	r := indexString(t, x, indexDoc)
+
+	// an indexed value, after which the decoder continues with the rest of the document
+	d, ok, err := r.DecoderAt("items", 1)
+	require.NoError(t, err)
+	require.True(t, ok)
+	var item struct{ Name string }
+	require.NoError(t, d.Decode(&item))
+	assert.Equal(t, "two", item.Name)
+	assert.Equal(t, JsonPath{"items", 1}, d.Path())
+
+	tok, err := d.Token()
+	require.NoError(t, err)
+	assert.Equal(t, json.Delim('{'), tok)
+	tok, err = d.Token()
+	require.NoError(t, err)
+	assert.Equal(t, KeyString("id"), tok)
+	assert.Equal(t, JsonPath{"items", 2, "id"}, d.Path())
+	ok, err = d.SeekTo("end")
+	require.NoError(t, err)
+	require.True(t, ok)
+	var end bool
+	require.NoError(t, d.Decode(&end))
+	assert.True(t, end)
+	for {
+		if _, err = d.Token(); err != nil {
+			break
+		}
+	}
+	assert.Equal(t, io.EOF, err)
+
+	// a value within an indexed value
+	d, ok, err = r.DecoderAt("items", 1, "parts", 0, "sku")
+	require.NoError(t, err)
+	require.True(t, ok)
+	var sku string
+	require.NoError(t, d.Decode(&sku))
+	assert.Equal(t, "x", sku)
+
+	// a value with no indexed value above it is found from the beginning
+	d, ok, err = r.DecoderAt("meta", "tags", 1)
+	require.NoError(t, err)
+	require.True(t, ok)
+	var tag string
+	require.NoError(t, d.Decode(&tag))
+	assert.Equal(t, "b", tag)
+
+	// Scan from an indexed element matches paths relative to it
+	d, _, err = r.DecoderAt("items", 2)
+	require.NoError(t, err)
+	var names []string
+	actions := &PathActions{}
+	actions.Add(func(d *Decoder) error {
+		var s string
+		names = append(names, s)
+		return d.Decode(&names[len(names)-1])
+	}, "name")
+	_, err = d.Scan(actions)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"three"}, names)
+
+	_, ok, err = r.DecoderAt("items", 5)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestIndexErrors(t *testing.T) {
+
+	for _, index := range []string{"", "JPIX", "JPIX\x02", "JPIX\x01\x05", "JPIX\x01\x00\x01\x09"} {
+		_, err := NewIndexedReader(strings.NewReader(indexDoc), int64(len(indexDoc)), strings.NewReader(index))
+		assert.Equal(t, ErrInvalidIndex, err, "%q", index)
+	}
+
+	x := &Indexer{Depth: 2}
+	err := x.Index(NewDecoder(strings.NewReader(`{"a":[1,}`)), &bytes.Buffer{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,408 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultInferExamples is the number of example values an Inferrer keeps for each path if Examples is zero.
+const DefaultInferExamples = 3
+
+// Inferrer infers the shape of JSON values from samples. Each value is read from a Decoder a token at a time, and
+// the types seen at each path are merged with those of the values read before. Array elements are merged under
+// AnyIndex, so every element of an array is described by a single path.
+//
+// An Inferrer must not be used concurrently.
+type Inferrer struct {
+	// Examples is the maximum number of distinct scalar values kept as examples for each path. If it is zero,
+	// DefaultInferExamples is used; if it is negative, no examples are kept.
+	Examples int
+
+	root *inferNode
+}
+
+// inferNode holds what has been seen at one path.
+type inferNode struct {
+	count    int            // the number of values seen
+	types    map[string]int // the number of values of each type: null, boolean, integer, number, string, object or array
+	keys     []string       // the object keys, in the order they were first seen
+	props    map[string]*inferNode
+	items    *inferNode // the array elements
+	examples []interface{}
+}
+
+// InferredField describes the values seen at one path.
+type InferredField struct {
+	Path     JsonPath      // the path, with AnyIndex in place of array indices
+	Types    []string      // the JSON Schema types seen, sorted, where integer is a number without a fraction or exponent
+	Count    int           // the number of values seen
+	Optional bool          // the value is missing from some of the objects that contain it
+	Examples []interface{} // distinct scalar values seen, in the order they were first seen
+}
+
+// Infer reads the next value from d and merges its shape into what the Inferrer has seen.
+func (in *Inferrer) Infer(d *Decoder) error {
+	if in.root == nil {
+		in.root = &inferNode{}
+	}
+	kind, err := d.next()
+	if err != nil {
+		return err
+	}
+	return in.value(d, kind, in.root)
+}
+
+// value merges the value that begins with the token of the given kind into n.
+func (in *Inferrer) value(d *Decoder, kind tokenKind, n *inferNode) error {
+
+	n.count++
+	if n.types == nil {
+		n.types = map[string]int{}
+	}
+	switch kind {
+	case tokenObjectStart:
+		n.types["object"]++
+		for {
+			kind, err := d.next()
+			if err != nil {
+				return err
+			}
+			if kind == tokenObjectEnd {
+				return nil
+			}
+			key := string(d.path.top().key)
+			c, ok := n.props[key]
+			if !ok {
+				if n.props == nil {
+					n.props = map[string]*inferNode{}
+				}
+				c = &inferNode{}
+				n.props[key] = c
+				n.keys = append(n.keys, key)
+			}
+			if kind, err = d.next(); err != nil {
+				return err
+			}
+			if err := in.value(d, kind, c); err != nil {
+				return err
+			}
+		}
+
+	case tokenArrayStart:
+		n.types["array"]++
+		for {
+			kind, err := d.next()
+			if err != nil {
+				return err
+			}
+			if kind == tokenArrayEnd {
+				return nil
+			}
+			if n.items == nil {
+				n.items = &inferNode{}
+			}
+			if err := in.value(d, kind, n.items); err != nil {
+				return err
+			}
+		}
+	}
+
+	var v interface{}
+	switch kind {
+	case tokenString:
+		n.types["string"]++
+		v = d.tok.string()
+	case tokenNumber:
+		if bytes.ContainsAny(d.tok.raw, ".eE") {
+			n.types["number"]++
+		} else {
+			n.types["integer"]++
+		}
+		v = json.Number(d.tok.raw)
+	case tokenTrue, tokenFalse:
+		n.types["boolean"]++
+		v = kind == tokenTrue
+	default:
+		n.types["null"]++
+	}
+	in.example(n, v)
+	return nil
+}
+
+// example adds v to the examples of n if it is new and there is room.
+func (in *Inferrer) example(n *inferNode, v interface{}) {
+	max := in.Examples
+	if max == 0 {
+		max = DefaultInferExamples
+	}
+	if v == nil || len(n.examples) >= max {
+		return
+	}
+	for _, e := range n.examples {
+		if e == v {
+			return
+		}
+	}
+	n.examples = append(n.examples, v)
+}
+
+// Fields returns a description of each path seen, in depth-first order with object keys in the order they were
+// first seen. The first field is the root.
+func (in *Inferrer) Fields() []InferredField {
+	if in.root == nil {
+		return nil
+	}
+	return in.root.fields(nil, JsonPath{}, false)
+}
+
+func (n *inferNode) fields(fields []InferredField, path JsonPath, optional bool) []InferredField {
+	fields = append(fields, InferredField{
+		Path:     path,
+		Types:    n.typeList(),
+		Count:    n.count,
+		Optional: optional,
+		Examples: n.examples,
+	})
+	for _, k := range n.keys {
+		c := n.props[k]
+		fields = c.fields(fields, append(path[:len(path):len(path)], k), c.count < n.types["object"])
+	}
+	if n.items != nil {
+		fields = n.items.fields(fields, append(path[:len(path):len(path)], AnyIndex), false)
+	}
+	return fields
+}
+
+// typeList returns the types seen, sorted.
+func (n *inferNode) typeList() []string {
+	types := make([]string, 0, len(n.types))
+	for t := range n.types {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// JSONSchema returns a draft 2020-12 JSON Schema that describes the values seen. Properties present in every object
+// seen at a path are required, and integers are merged with numbers if both are seen at a path. Examples are
+// included with the examples keyword.
+func (in *Inferrer) JSONSchema() ([]byte, error) {
+	s := map[string]interface{}{}
+	if in.root != nil {
+		s = in.root.schema()
+	}
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	return json.MarshalIndent(s, "", "  ")
+}
+
+func (n *inferNode) schema() map[string]interface{} {
+	s := map[string]interface{}{}
+	types := n.typeList()
+	if n.types["integer"] > 0 && n.types["number"] > 0 {
+		types = removeString(types, "integer")
+	}
+	if len(types) == 1 {
+		s["type"] = types[0]
+	} else if len(types) > 1 {
+		s["type"] = types
+	}
+
+	if n.props != nil {
+		props := map[string]interface{}{}
+		var required []string
+		for _, k := range n.keys {
+			c := n.props[k]
+			props[k] = c.schema()
+			if c.count == n.types["object"] {
+				required = append(required, k)
+			}
+		}
+		s["properties"] = props
+		if len(required) > 0 {
+			s["required"] = required
+		}
+	}
+	if n.items != nil {
+		s["items"] = n.items.schema()
+	}
+	if len(n.examples) > 0 {
+		s["examples"] = n.examples
+	}
+	return s
+}
+
+func removeString(list []string, s string) []string {
+	out := list[:0]
+	for _, e := range list {
+		if e != s {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// GoType returns the source of a Go type declaration named name for the values seen, formatted with gofmt. Objects
+// become structs whose fields have json tags, with names derived from the keys. Properties that are missing from
+// some objects are tagged omitempty. A value that is sometimes null becomes a pointer, and a value seen with more
+// than one other type becomes an interface{}.
+func (in *Inferrer) GoType(name string) (string, error) {
+	root := in.root
+	if root == nil {
+		root = &inferNode{}
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "type %s ", name)
+	root.goType(&b)
+	src, err := format.Source(b.Bytes())
+	return string(src), err
+}
+
+// GoTypeAt returns the Go type of the values seen at path, as it appears in the declaration written by GoType. Both
+// AnyIndex and array indices match every element of an array. The type is not formatted. GoTypeAt returns false if
+// no values were seen at path.
+func (in *Inferrer) GoTypeAt(path JsonPath) (string, bool) {
+	n := in.root
+	for _, e := range path {
+		if n == nil {
+			break
+		}
+		if key, ok := e.(string); ok {
+			n = n.props[key]
+		} else {
+			n = n.items
+		}
+	}
+	if n == nil {
+		return "", false
+	}
+	var b bytes.Buffer
+	n.goType(&b)
+	return b.String(), true
+}
+
+// goType writes the Go type of the values seen at n.
+func (n *inferNode) goType(b *bytes.Buffer) {
+
+	types := n.typeList()
+	nullable := n.types["null"] > 0
+	if nullable {
+		types = removeString(types, "null")
+	}
+	if n.types["integer"] > 0 && n.types["number"] > 0 {
+		types = removeString(types, "integer")
+	}
+	if len(types) != 1 {
+		b.WriteString("interface{}")
+		return
+	}
+
+	switch types[0] {
+	case "object":
+		if len(n.keys) == 0 {
+			b.WriteString("map[string]interface{}")
+			return
+		}
+		if nullable {
+			b.WriteByte('*')
+		}
+		b.WriteString("struct {\n")
+		names := map[string]bool{}
+		for _, k := range n.keys {
+			c := n.props[k]
+			name := goFieldName(k, names)
+			names[name] = true
+			b.WriteString(name)
+			b.WriteByte(' ')
+			c.goType(b)
+
+			tag := k
+			if c.count < n.types["object"] {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(b, " `json:%q`\n", tag)
+		}
+		b.WriteString("}")
+	case "array":
+		b.WriteString("[]")
+		if n.items == nil {
+			b.WriteString("interface{}")
+			return
+		}
+		n.items.goType(b)
+	default:
+		if nullable {
+			b.WriteByte('*')
+		}
+		b.WriteString(map[string]string{"boolean": "bool", "integer": "int64", "number": "float64",
+			"string": "string"}[types[0]])
+	}
+}
+
+// goInitialisms are the words that are written in upper case in generated field names.
+var goInitialisms = map[string]bool{
+	"API": true, "CPU": true, "CSS": true, "DNS": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true,
+	"IP": true, "JSON": true, "SKU": true, "SQL": true, "TCP": true, "TLS": true, "TTL": true, "UI": true,
+	"URI": true, "URL": true, "UUID": true, "XML": true,
+}
+
+// goFieldName returns an exported Go identifier for the object key, which is not one of used.
+func goFieldName(key string, used map[string]bool) string {
+	name := GoName(JsonPath{key})
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	return unique
+}
+
+// GoName returns an exported Go identifier made from the keys of path, such as LinesSKU for $.lines[*].sku. The
+// keys are split into words at punctuation and at changes from lower to upper case, and each word is capitalized, or
+// written in upper case if it is a common initialism such as ID or URL. Array indices are ignored.
+func GoName(path JsonPath) string {
+
+	var words []string
+	for _, e := range path {
+		key, ok := e.(string)
+		if !ok {
+			continue
+		}
+		var word []rune
+		prev := rune(0)
+		for _, r := range key {
+			switch {
+			case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+				if len(word) > 0 {
+					words, word = append(words, string(word)), nil
+				}
+			case unicode.IsUpper(r) && unicode.IsLower(prev) && len(word) > 0:
+				words, word = append(words, string(word)), []rune{r}
+			default:
+				word = append(word, r)
+			}
+			prev = r
+		}
+		if len(word) > 0 {
+			words = append(words, string(word))
+		}
+	}
+
+	var b strings.Builder
+	for _, w := range words {
+		if u := strings.ToUpper(w); goInitialisms[u] {
+			b.WriteString(u)
+			continue
+		}
+		r := []rune(w)
+		b.WriteString(string(unicode.ToUpper(r[0])) + string(r[1:]))
+	}
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "F" + name
+	}
+	return name
+}
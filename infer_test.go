@@ -0,0 +1,169 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const inferSamples = `
+{"id":1,"user_name":"ann","tags":["a","b"],"lines":[{"sku":"x1","qty":2},{"sku":"x2","qty":1.5,"note":null}],"ref":null}
+{"id":2,"user_name":"bob","tags":[],"lines":[{"sku":"x1","qty":3}],"ref":"r-9","extra":{}}
+`
+
+func inferString(t *testing.T, in *Inferrer, s string) {
+	d := NewDecoder(strings.NewReader(s))
+	for d.More() {
+		require.NoError(t, in.Infer(d))
+	}
+	_, err := d.Token()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestInferFields(t *testing.T) {
+
+	var in Inferrer
+	assert.Nil(t, in.Fields())
+	inferString(t, &in, inferSamples)
+
+	fields := in.Fields()
+	paths := make([]string, len(fields))
+	for i, f := range fields {
+		paths[i] = FormatPath(f.Path)
+	}
+	assert.Equal(t, []string{"$", "$.id", "$.user_name", "$.tags", "$.tags[*]", "$.lines", "$.lines[*]",
+		"$.lines[*].sku", "$.lines[*].qty", "$.lines[*].note", "$.ref", "$.extra"}, paths)
+
+	byPath := map[string]InferredField{}
+	for i, f := range fields {
+		byPath[paths[i]] = f
+	}
+	assert.Equal(t, []string{"object"}, byPath["$"].Types)
+	assert.Equal(t, 2, byPath["$"].Count)
+	assert.Equal(t, []interface{}{json.Number("1"), json.Number("2")}, byPath["$.id"].Examples)
+	assert.Equal(t, 3, byPath["$.lines[*]"].Count)
+	assert.Equal(t, []string{"integer", "number"}, byPath["$.lines[*].qty"].Types)
+	assert.False(t, byPath["$.lines[*].sku"].Optional)
+	assert.Equal(t, []interface{}{"x1", "x2"}, byPath["$.lines[*].sku"].Examples)
+	assert.True(t, byPath["$.lines[*].note"].Optional)
+	assert.Equal(t, []string{"null"}, byPath["$.lines[*].note"].Types)
+	assert.Nil(t, byPath["$.lines[*].note"].Examples)
+	assert.Equal(t, []string{"null", "string"}, byPath["$.ref"].Types)
+	assert.True(t, byPath["$.extra"].Optional)
+}
+
+func TestInferExamples(t *testing.T) {
+
+	in := Inferrer{Examples: 2}
+	inferString(t, &in, `["a","b","a","c",true]`)
+	assert.Equal(t, []interface{}{"a", "b"}, in.Fields()[1].Examples)
+
+	in = Inferrer{Examples: -1}
+	inferString(t, &in, `["a"]`)
+	assert.Nil(t, in.Fields()[1].Examples)
+}
+
+func TestInferJSONSchema(t *testing.T) {
+
+	var in Inferrer
+	inferString(t, &in, inferSamples)
+	b, err := in.JSONSchema()
+	require.NoError(t, err)
+
+	var s map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &s))
+	assert.Equal(t, "https://json-schema.org/draft/2020-12/schema", s["$schema"])
+	assert.Equal(t, "object", s["type"])
+	assert.Equal(t, []interface{}{"id", "user_name", "tags", "lines", "ref"}, s["required"])
+
+	props := s["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "integer", "examples": []interface{}{1.0, 2.0}}, props["id"])
+	assert.Equal(t, []interface{}{"null", "string"}, props["ref"].(map[string]interface{})["type"])
+	assert.Equal(t, map[string]interface{}{"type": "object"}, props["extra"])
+
+	line := props["lines"].(map[string]interface{})["items"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"sku", "qty"}, line["required"])
+	qty := line["properties"].(map[string]interface{})["qty"].(map[string]interface{})
+	assert.Equal(t, "number", qty["type"])
+
+	// the inferred schema accepts the samples it was inferred from
+	schema, err := CompileSchema(b)
+	require.NoError(t, err)
+	d := NewDecoder(strings.NewReader(inferSamples))
+	for d.More() {
+		errs, err := schema.Validate(d)
+		require.NoError(t, err)
+		assert.Empty(t, errs)
+	}
+}
+
+func TestInferGoType(t *testing.T) {
+
+	var in Inferrer
+	inferString(t, &in, inferSamples)
+	src, err := in.GoType("Order")
+	require.NoError(t, err)
+	assert.Equal(t, "type Order struct {\n"+
+		"\tID       int64    `json:\"id\"`\n"+
+		"\tUserName string   `json:\"user_name\"`\n"+
+		"\tTags     []string `json:\"tags\"`\n"+
+		"\tLines    []struct {\n"+
+		"\t\tSKU  string      `json:\"sku\"`\n"+
+		"\t\tQty  float64     `json:\"qty\"`\n"+
+		"\t\tNote interface{} `json:\"note,omitempty\"`\n"+
+		"\t} `json:\"lines\"`\n"+
+		"\tRef   *string                `json:\"ref\"`\n"+
+		"\tExtra map[string]interface{} `json:\"extra,omitempty\"`\n"+
+		"}", src)
+}
+
+func TestGoFieldName(t *testing.T) {
+	for key, name := range map[string]string{
+		"id":         "ID",
+		"userName":   "UserName",
+		"api_url":    "APIURL",
+		"2fa":        "F2fa",
+		"":           "F",
+		"naïve_café": "NaïveCafé",
+	} {
+		assert.Equal(t, name, goFieldName(key, nil), key)
+	}
+	assert.Equal(t, "UserName2", goFieldName("user-name", map[string]bool{"UserName": true}))
+}
+
+func TestGoName(t *testing.T) {
+	assert.Equal(t, "LinesSKU", GoName(JsonPath{"lines", AnyIndex, "sku"}))
+	assert.Equal(t, "CustomerEmailAddress", GoName(JsonPath{"customer", 0, "emailAddress"}))
+	assert.Equal(t, "F", GoName(JsonPath{}))
+}
+
+func TestInferGoTypeAt(t *testing.T) {
+
+	var in Inferrer
+	_, ok := in.GoTypeAt(JsonPath{})
+	assert.False(t, ok)
+
+	inferString(t, &in, inferSamples)
+	for _, tst := range []struct {
+		path JsonPath
+		typ  string
+	}{
+		{JsonPath{"id"}, "int64"},
+		{JsonPath{"lines", AnyIndex, "qty"}, "float64"},
+		{JsonPath{"lines", 1, "sku"}, "string"},
+		{JsonPath{"tags"}, "[]string"},
+		{JsonPath{"ref"}, "*string"},
+	} {
+		typ, ok := in.GoTypeAt(tst.path)
+		assert.True(t, ok, "%v", tst.path)
+		assert.Equal(t, tst.typ, typ, "%v", tst.path)
+	}
+	_, ok = in.GoTypeAt(JsonPath{"lines", AnyIndex, "missing"})
+	assert.False(t, ok)
+	_, ok = in.GoTypeAt(JsonPath{"id", "x"})
+	assert.False(t, ok)
+}
@@ -0,0 +1,96 @@
+package jsonpath
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Limits restricts the resources a Decoder will consume while reading a JSON stream. It is intended for use when
+// decoding untrusted input. A zero value for any field means that the corresponding resource is not limited.
+type Limits struct {
+	// MaxDepth is the maximum nesting depth of objects and arrays.
+	MaxDepth int
+	// MaxStringLength is the maximum length, in bytes, of any object key or string value as it appears in the
+	// input, excluding the quotes. An escape sequence counts for its length in the input, so "\u00e9" is six bytes.
+	MaxStringLength int
+	// MaxTokens is the maximum number of tokens that will be read. A value consumed with Decode, or skipped by
+	// Scan, counts as one token.
+	MaxTokens int
+	// MaxBytes is the maximum number of bytes that will be read from the underlying reader.
+	MaxBytes int64
+}
+
+// LimitError is returned by the Decoder when the input exceeds one of the configured Limits.
+type LimitError struct {
+	Limit string   // name of the Limits field that was exceeded, e.g. "MaxDepth"
+	Max   int64    // the configured value of the limit
+	Path  JsonPath // path of the token that exceeded the limit
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("jsonpath: %s of %d exceeded at %v", e.Limit, e.Max, e.Path)
+}
+
+// SetLimits configures the resource limits enforced by the Decoder. The nesting depth and string lengths of values
+// consumed with Decode or skipped by Scan are checked as they are read. Once a limit is exceeded the Decoder returns
+// a *LimitError from every subsequent call.
+func (d *Decoder) SetLimits(l Limits) {
+	d.limits = l
+	d.input.max = l.MaxBytes
+	d.tok.maxDepth = l.MaxDepth
+	d.tok.maxString = l.MaxStringLength
+	d.tok.maxTokens = l.MaxTokens
+}
+
+func (d *Decoder) limitError(limit string, max int64) error {
+	d.err = &LimitError{Limit: limit, Max: max, Path: d.Path()}
+	return d.err
+}
+
+// readError translates an error from the tokenizer, converting the violation of a limit into a *LimitError. Errors
+// are sticky.
+func (d *Decoder) readError(err error) error {
+	switch {
+	case errors.Is(err, errByteLimit):
+		return d.limitError("MaxBytes", d.limits.MaxBytes)
+	case err == errDepthLimit:
+		return d.limitError("MaxDepth", int64(d.limits.MaxDepth))
+	case err == errStringLimit:
+		return d.limitError("MaxStringLength", int64(d.limits.MaxStringLength))
+	case err == errTokenLimit:
+		return d.limitError("MaxTokens", int64(d.limits.MaxTokens))
+	}
+	if err != io.EOF {
+		d.err = err
+	}
+	return err
+}
+
+var errByteLimit = errors.New("jsonpath: byte limit exceeded")
+
+// countingReader counts the bytes read from r and refuses to read more than max bytes when max is positive.
+type countingReader struct {
+	r   io.Reader
+	n   int64
+	max int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	if c.max > 0 {
+		if c.n >= c.max {
+			// Allow input that is exactly max bytes long to reach EOF.
+			var b [1]byte
+			if n, err := io.ReadFull(c.r, b[:]); n == 0 {
+				return 0, err
+			}
+			return 0, errByteLimit
+		}
+		if rem := c.max - c.n; int64(len(p)) > rem {
+			p = p[:rem]
+		}
+	}
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
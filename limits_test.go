@@ -0,0 +1,137 @@
+package jsonpath
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAllTokens(d *Decoder) error {
+	for {
+		_, err := d.Token()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+
+	tests := []struct {
+		in     string
+		limits Limits
+		limit  string
+		path   JsonPath
+	}{
+		{in: `{"a":[[1]]}`, limits: Limits{MaxDepth: 3}},
+		{in: `{"a":[[[1]]]}`, limits: Limits{MaxDepth: 3}, limit: "MaxDepth", path: JsonPath{"a", 0, 0, -1}},
+		{in: `[{},{"b":{}}]`, limits: Limits{MaxDepth: 2}, limit: "MaxDepth", path: JsonPath{1, "b", ""}},
+		{in: `{"abc":"defg"}`, limits: Limits{MaxStringLength: 4}},
+		{in: `{"abcde":"x"}`, limits: Limits{MaxStringLength: 4}, limit: "MaxStringLength", path: JsonPath{"abcde"}},
+		{in: `{"a":["x","abcde"]}`, limits: Limits{MaxStringLength: 4}, limit: "MaxStringLength", path: JsonPath{"a", 1}},
+		{in: `{"a":"\u00e9"}`, limits: Limits{MaxStringLength: 6}},
+		{in: `{"a":"\u00e9"}`, limits: Limits{MaxStringLength: 5}, limit: "MaxStringLength", path: JsonPath{"a"}},
+		{in: `[1,2,3]`, limits: Limits{MaxTokens: 5}},
+		{in: `[1,2,3,4,5]`, limits: Limits{MaxTokens: 5}, limit: "MaxTokens", path: JsonPath{4}},
+		{in: `[1,2,3]`, limits: Limits{MaxBytes: 7}},
+		{in: `[1,2,3] `, limits: Limits{MaxBytes: 7}, limit: "MaxBytes", path: JsonPath{}},
+		{in: `[1,2,3,4]`, limits: Limits{MaxBytes: 7}, limit: "MaxBytes", path: JsonPath{2}},
+	}
+
+	for i, tst := range tests {
+		d := NewDecoder(strings.NewReader(tst.in))
+		d.SetLimits(tst.limits)
+
+		err := readAllTokens(d)
+		if tst.limit == "" {
+			assert.NoError(t, err, "#%v %s", i, tst.in)
+			continue
+		}
+
+		le, ok := err.(*LimitError)
+		if assert.True(t, ok, "#%v %s: expected *LimitError, was %v", i, tst.in, err) {
+			assert.Equal(t, tst.limit, le.Limit, "#%v %s", i, tst.in)
+			assert.Equal(t, tst.path, le.Path, "#%v %s", i, tst.in)
+		}
+
+		// errors are sticky
+		_, err2 := d.Token()
+		assert.Equal(t, err, err2)
+	}
+}
+
+func TestLimitsDecode(t *testing.T) {
+
+	d := NewDecoder(bytes.NewBufferString(`{"a":[1,2,3],"b":{"c":"d"},"e":0}`))
+	d.SetLimits(Limits{MaxTokens: 5})
+
+	ok, err := d.SeekTo("a")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	var v interface{}
+	require.NoError(t, d.Decode(&v))
+	assert.Equal(t, []interface{}{float64(1), float64(2), float64(3)}, v)
+
+	_, err = d.SeekTo("e")
+	assert.Equal(t, &LimitError{Limit: "MaxTokens", Max: 5, Path: JsonPath{"b", "c"}}, err)
+	assert.Equal(t, err, d.Decode(&v))
+}
+
+func TestLimitsDecodeNested(t *testing.T) {
+
+	d := NewDecoder(bytes.NewBufferString(`{"a":[[[1]]]}`))
+	d.SetLimits(Limits{MaxDepth: 3})
+
+	ok, err := d.SeekTo("a")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	var v interface{}
+	err = d.Decode(&v)
+	assert.Equal(t, &LimitError{Limit: "MaxDepth", Max: 3, Path: JsonPath{"a"}}, err)
+}
+
+// repeatReader reads an endless run of the byte c.
+type repeatReader byte
+
+func (r repeatReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r)
+	}
+	return len(p), nil
+}
+
+func TestLimitsStringLength(t *testing.T) {
+
+	// a string is rejected once it exceeds the limit, without reading the rest of it
+	for _, skip := range []bool{false, true} {
+		d := NewDecoder(io.MultiReader(strings.NewReader(`["`), repeatReader('x')))
+		d.SetLimits(Limits{MaxStringLength: 100})
+		_, err := d.Token()
+		require.NoError(t, err)
+
+		if skip {
+			var v interface{}
+			err = d.Decode(&v)
+		} else {
+			_, err = d.Token()
+		}
+		if assert.IsType(t, &LimitError{}, err, "skip %v", skip) {
+			assert.Equal(t, "MaxStringLength", err.(*LimitError).Limit)
+		}
+		assert.True(t, d.input.n < 1<<16, "read %d bytes", d.input.n)
+	}
+
+	// escapes count for their length in the input whether or not the value is decoded
+	d := NewDecoder(strings.NewReader(`["\u00e9"]`))
+	d.SetLimits(Limits{MaxStringLength: 5})
+	var v interface{}
+	assert.IsType(t, &LimitError{}, d.Decode(&v))
+}
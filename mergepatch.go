@@ -0,0 +1,152 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// MergePatch is an RFC 7386 JSON Merge Patch.
+type MergePatch json.RawMessage
+
+// Apply copies the remainder of the JSON stream from d to e, merging the patch into each top-level value.
+//
+// The patch is held in memory but the source document is not: objects in the source that the patch modifies are
+// merged member by member as they stream through, and every other value is copied as it appears in the input.
+// Members that the patch adds to an object are written at the end of the object, in the order of their keys. As
+// the RFC specifies, a null in the patch removes the corresponding member, and arrays are replaced rather than
+// merged.
+func (p MergePatch) Apply(d *Decoder, e *Encoder) error {
+
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	var patch interface{}
+	if err := dec.Decode(&patch); err != nil {
+		return err
+	}
+
+	for {
+		if !d.More() {
+			// the end of the stream, or an error
+			_, err := d.next()
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := mergeValue(d, e, patch); err != nil {
+			return err
+		}
+	}
+}
+
+// mergeValue merges patch into the next value from d, writing the result to e.
+func mergeValue(d *Decoder, e *Encoder, patch interface{}) error {
+
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		// the patch replaces the value
+		if _, err := d.skip(false); err != nil {
+			return err
+		}
+		return e.WriteValue(patch)
+	}
+
+	kind, err := d.next()
+	if err != nil {
+		return err
+	}
+	if kind != tokenObjectStart {
+		// the value is not an object, so the patch is merged into an empty object instead
+		if kind == tokenArrayStart {
+			for d.More() {
+				if _, err := d.skip(false); err != nil {
+					return err
+				}
+			}
+			if _, err := d.next(); err != nil {
+				return err
+			}
+		}
+		return e.WriteValue(pruneNulls(members))
+	}
+
+	if err := e.WriteToken(json.Delim('{')); err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(members))
+	for d.More() {
+		if _, err := d.next(); err != nil {
+			return err
+		}
+		key := string(d.path.top().key)
+		v, ok := members[key]
+		if !ok {
+			// the member is not patched, so copy it as it is
+			if err := e.writeKey(key, d.tok.raw); err != nil {
+				return err
+			}
+			raw, err := d.skip(true)
+			if err != nil {
+				return err
+			}
+			if err := e.writeRaw(raw); err != nil {
+				return err
+			}
+			continue
+		}
+
+		seen[key] = true
+		if v == nil {
+			if _, err := d.skip(false); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := e.writeKey(key, d.tok.raw); err != nil {
+			return err
+		}
+		if err := mergeValue(d, e, v); err != nil {
+			return err
+		}
+	}
+
+	// add the members that were not in the source
+	keys := make([]string, 0, len(members))
+	for k, v := range members {
+		if !seen[k] && v != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := e.WriteKey(k); err != nil {
+			return err
+		}
+		if err := e.WriteValue(pruneNulls(members[k])); err != nil {
+			return err
+		}
+	}
+
+	if _, err := d.next(); err != nil {
+		return err
+	}
+	return e.WriteToken(json.Delim('}'))
+}
+
+// pruneNulls returns the result of merging patch into an empty object, which removes the members of objects that
+// are null.
+func pruneNulls(patch interface{}) interface{} {
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	out := make(map[string]interface{}, len(members))
+	for k, v := range members {
+		if v != nil {
+			out[k] = pruneNulls(v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePatchApply(t *testing.T) {
+
+	tests := []struct {
+		doc   string
+		patch string
+		out   string
+	}{
+		// examples from RFC 7386 appendix A
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+
+		// untouched values are copied as they are, and new members are added in key order
+		{`{"n":1.50,"o":{"x":[1, 2]},"p":{"q":1}}`, `{"z":1,"p":{"r":2},"m":1e3}`, `{"n":1.50,"o":{"x":[1, 2]},"p":{"q":1,"r":2},"m":1e3,"z":1}`},
+		{`{"a":{"b":1}}`, `{"a":{"b":{"c":null,"d":[null]}}}`, `{"a":{"b":{"d":[null]}}}`},
+	}
+
+	for _, tst := range tests {
+		var out bytes.Buffer
+		err := MergePatch(tst.patch).Apply(NewDecoder(strings.NewReader(tst.doc)), NewEncoder(&out))
+		if assert.NoError(t, err, tst.patch) {
+			assert.Equal(t, tst.out+"\n", out.String(), "%s %s", tst.doc, tst.patch)
+		}
+	}
+}
+
+func TestMergePatchStream(t *testing.T) {
+
+	var out bytes.Buffer
+	err := MergePatch(`{"a":null,"b":true}`).Apply(NewDecoder(strings.NewReader(`{"a":1} {"c":2} 3`)), NewEncoder(&out))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"b\":true}\n{\"c\":2,\"b\":true}\n{\"b\":true}\n", out.String())
+}
+
+func TestMergePatchErrors(t *testing.T) {
+
+	var out bytes.Buffer
+	err := MergePatch(`{"a"`).Apply(NewDecoder(strings.NewReader(`{}`)), NewEncoder(&out))
+	assert.Error(t, err)
+
+	err = MergePatch(`{"a":{"b":1}}`).Apply(NewDecoder(strings.NewReader(`{"a":{"b":}}`)), NewEncoder(&out))
+	assert.IsType(t, &json.SyntaxError{}, err)
+}
@@ -0,0 +1,663 @@
+package jsonpath
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PatchOperation is a single operation of an RFC 6902 JSON Patch.
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Patch is an RFC 6902 JSON Patch. A Patch can be unmarshalled from its JSON representation with encoding/json.
+type Patch []PatchOperation
+
+// PatchError is returned when an operation of a Patch cannot be applied.
+type PatchError struct {
+	Index int    // index of the operation within the Patch
+	Op    string // the operation, e.g. "replace"
+	Path  string // the JSON Pointer of the operation's target
+	Err   error  // ErrPatchTestFailed, ErrPatchPathNotFound, ErrPatchUnsupported or a description of an invalid operation
+}
+
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("jsonpath: patch operation %d (%s %q): %v", e.Index, e.Op, e.Path, e.Err)
+}
+
+func (e *PatchError) Unwrap() error { return e.Err }
+
+var (
+	// ErrPatchTestFailed is the cause of a PatchError when the value at the target of a test operation differs from
+	// the operation's value.
+	ErrPatchTestFailed = errors.New("test failed")
+	// ErrPatchPathNotFound is the cause of a PatchError when a location referenced by an operation does not exist.
+	ErrPatchPathNotFound = errors.New("path not found")
+	// ErrPatchUnsupported is the cause of a PatchError when an operation cannot be applied in a single pass over the
+	// document. A move or copy operation requires that its from location is read before the location where its
+	// value is to be written.
+	ErrPatchUnsupported = errors.New("cannot be applied while streaming")
+)
+
+// errStageClosed is returned to a patch stage when the following stage has stopped reading its output.
+var errStageClosed = errors.New("jsonpath: patch stage closed")
+
+// Apply copies the remainder of the JSON stream from d to e, applying the patch to each top-level value. Paths are
+// matched from the root of the stream, so Apply is normally called before any tokens have been read from d.
+//
+// The operations are applied in sequence as the document streams through, so that the memory used depends on the
+// sizes of the values in the patch rather than on the size of the document. Consecutive replace and test operations
+// whose locations are not within one another are applied together in a single pass over the document, and any other
+// operation is a pass of its own. Each pass runs in its own goroutine on the output of the previous one, so the time
+// taken grows with the number of passes times the size of the document. A move or copy operation holds its value in
+// memory, and requires that its from location appears in the document before the location where the value is
+// written; a value added to an object is written at the end of the object.
+//
+// If an operation cannot be applied, Apply returns a *PatchError. The output written before an error is incomplete
+// and should be discarded.
+func (p Patch) Apply(d *Decoder, e *Encoder) error {
+
+	passes := make([]*patchPass, 0, len(p))
+	for i := range p {
+		s, err := newPatchStage(i, &p[i])
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			continue
+		}
+		if n := len(passes); n > 0 && passes[n-1].accepts(s) {
+			passes[n-1].stages = append(passes[n-1].stages, s)
+		} else {
+			passes = append(passes, &patchPass{stages: []*patchStage{s}})
+		}
+	}
+	if len(passes) == 0 {
+		return (&Transformer{}).Transform(d, e)
+	}
+
+	// connect the passes with pipes, running all but the last in their own goroutines
+	errs := make([]error, len(passes))
+	readers := make([]*io.PipeReader, 0, len(passes)-1)
+	var wg sync.WaitGroup
+	for i, s := range passes[:len(passes)-1] {
+		pr, pw := io.Pipe()
+		readers = append(readers, pr)
+		wg.Add(1)
+		go func(i int, s *patchPass, d *Decoder) {
+			defer wg.Done()
+			w := bufio.NewWriter(pw)
+			err := s.run(d, NewEncoder(w))
+			if err == nil {
+				err = w.Flush()
+			}
+			errs[i] = err
+			pw.CloseWithError(err)
+		}(i, s, d)
+		d = NewDecoder(pr)
+	}
+	errs[len(passes)-1] = passes[len(passes)-1].run(d, e)
+
+	// stop any passes that are still running and wait for them
+	for _, pr := range readers {
+		pr.CloseWithError(errStageClosed)
+	}
+	wg.Wait()
+
+	// report the error of the earliest pass that failed, which the later passes will have read from their input
+	for _, err := range errs {
+		if err != nil && err != errStageClosed {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchStage applies a single operation to a JSON stream.
+type patchStage struct {
+	index int
+	op    *PatchOperation
+	path  []string // reference tokens of the target
+	from  []string // reference tokens of the from location, for move and copy
+
+	// target is path in terms of the input of the current top-level value, which differs from path when a move
+	// removes an earlier element of an array on the way to the target
+	target []string
+
+	value   []byte // the value to write, or the value to compare for test
+	have    bool   // value has been set; for move and copy, when the from location has been read
+	done    bool   // the operation has been applied to the current top-level value
+	started bool   // a top-level value has been started
+}
+
+// newPatchStage validates op and returns a stage that applies it, or nil if the operation has no effect.
+func newPatchStage(index int, op *PatchOperation) (*patchStage, error) {
+	s := &patchStage{index: index, op: op}
+	var err error
+	if s.path, err = parsePointer(op.Path); err != nil {
+		return nil, s.error(err)
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, s.error(errors.New("missing value"))
+		}
+		var b bytes.Buffer
+		if err := json.Compact(&b, op.Value); err != nil {
+			return nil, s.error(err)
+		}
+		s.value, s.have = b.Bytes(), true
+	case "remove":
+		if len(s.path) == 0 {
+			return nil, s.error(errors.New("cannot remove the root"))
+		}
+	case "move", "copy":
+		if s.from, err = parsePointer(op.From); err != nil {
+			return nil, s.error(err)
+		}
+		if op.Op == "move" && op.From == op.Path {
+			return nil, nil
+		}
+		if hasPointerPrefix(s.path, s.from) && len(s.path) > len(s.from) {
+			if op.Op == "move" {
+				return nil, s.error(errors.New("cannot move a value into itself"))
+			}
+			return nil, s.error(ErrPatchUnsupported)
+		}
+	default:
+		return nil, s.error(fmt.Errorf("unknown operation %q", op.Op))
+	}
+	for i, t := range s.path {
+		if t == "-" && i < len(s.path)-1 {
+			return nil, s.error(errors.New(`"-" may only be the last reference token`))
+		}
+	}
+	return s, nil
+}
+
+func (s *patchStage) error(err error) error {
+	return &PatchError{Index: s.index, Op: s.op.Op, Path: s.op.Path, Err: err}
+}
+
+// patchPass is a sequence of operations applied in a single pass over a JSON stream. Either it holds a single
+// operation, or the operations only replace or test values and none of their locations is within another, so that
+// they do not affect one another.
+type patchPass struct {
+	stages []*patchStage
+	root   *patchNode   // the locations of the operations, if there are more than one
+	nodes  []*patchNode // the node of each open object or array, or nil if no location is within it
+	index  []byte       // scratch space for formatting an array index
+}
+
+// patchNode is a node of the trie of the locations of the operations of a patchPass.
+type patchNode struct {
+	stage    *patchStage           // the operation located at the node, if any
+	within   *patchStage           // an operation located below the node, if any
+	ends     []*patchStage         // the operations located at the children of the node, in patch order
+	children map[string]*patchNode // keyed by reference token
+}
+
+// accepts reports whether s can be applied in the same pass as the operations of p.
+func (p *patchPass) accepts(s *patchStage) bool {
+	for _, t := range append(p.stages[:len(p.stages):len(p.stages)], s) {
+		if t.op.Op != "replace" && t.op.Op != "test" {
+			return false
+		}
+	}
+	for _, t := range p.stages {
+		if hasPointerPrefix(s.path, t.path) || hasPointerPrefix(t.path, s.path) {
+			return false
+		}
+	}
+	return true
+}
+
+// buildTrie builds the trie of the locations of the operations, so that each value is matched with a single lookup.
+func (p *patchPass) buildTrie() {
+	p.root = &patchNode{}
+	for _, s := range p.stages {
+		n := p.root
+		for i, t := range s.path {
+			n.within = s
+			if i == len(s.path)-1 {
+				n.ends = append(n.ends, s)
+			}
+			c := n.children[t]
+			if c == nil {
+				if n.children == nil {
+					n.children = map[string]*patchNode{}
+				}
+				c = &patchNode{}
+				n.children[t] = c
+			}
+			n = c
+		}
+		n.stage = s
+	}
+}
+
+// run copies the JSON stream from d to e, applying the operations to each top-level value.
+func (p *patchPass) run(d *Decoder, e *Encoder) error {
+	if len(p.stages) > 1 {
+		p.buildTrie()
+	}
+	for {
+		switch d.context {
+		case objKey, arrValue:
+			if !d.More() {
+				if err := p.end(d, e); err != nil {
+					return err
+				}
+				if err := copyEnd(d, e); err != nil {
+					return err
+				}
+				if p.root != nil {
+					p.nodes = p.nodes[:len(p.nodes)-1]
+				}
+				continue
+			}
+			key := d.context == objKey
+			if key {
+				if _, err := d.next(); err != nil {
+					return err
+				}
+			}
+			if err := p.copyValue(d, e, key); err != nil {
+				return err
+			}
+
+		default:
+			for _, s := range p.stages {
+				if s.started {
+					if !s.done {
+						return s.error(ErrPatchPathNotFound)
+					}
+					s.done, s.started = false, false
+					s.have = s.have && s.from == nil
+				}
+			}
+			if !d.More() {
+				// the end of the stream, or an error
+				_, err := d.next()
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+			for _, s := range p.stages {
+				s.started = true
+				s.target = s.path
+			}
+			p.nodes = p.nodes[:0]
+			if err := p.copyValue(d, e, false); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// copyValue copies the next value from d to e with the operation located at or within the value, if any.
+func (p *patchPass) copyValue(d *Decoder, e *Encoder, key bool) error {
+	if p.root == nil {
+		return p.stages[0].copyValue(d, e, key)
+	}
+
+	// the reference token of the value, whose array index the Decoder has not yet counted
+	depth := len(d.path)
+	n := p.root
+	if depth > 0 {
+		n = nil
+		if parent := p.nodes[len(p.nodes)-1]; parent != nil {
+			f := d.path.top()
+			token := f.key
+			if f.array {
+				p.index = strconv.AppendInt(p.index[:0], int64(f.index+1), 10)
+				token = p.index
+			}
+			n = parent.children[string(token)]
+		}
+	}
+
+	s := p.stages[0]
+	if n != nil && n.stage != nil {
+		s = n.stage
+	} else if n != nil && n.within != nil {
+		s = n.within
+	}
+	if err := s.copyValue(d, e, key); err != nil {
+		return err
+	}
+	if len(d.path) > depth {
+		// the value is an object or array that has been opened
+		p.nodes = append(p.nodes, n)
+	}
+	return nil
+}
+
+// end applies the operations located at the end of the current object or array.
+func (p *patchPass) end(d *Decoder, e *Encoder) error {
+	stages := p.stages
+	if p.root != nil {
+		stages = nil
+		if n := p.nodes[len(p.nodes)-1]; n != nil {
+			stages = n.ends
+		}
+	}
+	for _, s := range stages {
+		if err := s.end(d, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyValue copies the next value from d to e, applying the operation if the value is its target or from location.
+// If key is true the value's key has been read from d but not yet written.
+func (s *patchStage) copyValue(d *Decoder, e *Encoder, key bool) error {
+
+	// match the path of the value, which the Decoder has not yet counted if it is an array element
+	inArray := d.context == arrValue
+	if inArray {
+		d.path.incTop()
+	}
+	target := pointerEqual(s.target, d.path)
+	from := s.from != nil && pointerEqual(s.from, d.path)
+	within := pointerPrefix(s.target, d.path) || s.from != nil && pointerPrefix(s.from, d.path)
+	fromWithin := s.from != nil && pointerPrefix(s.from, d.path)
+	if inArray {
+		d.path.top().index--
+	}
+
+	writeKey := func() error {
+		if key {
+			return e.writeKey(string(d.path.top().key), d.tok.raw)
+		}
+		return nil
+	}
+	last := ""
+	if len(s.target) > 0 {
+		last = s.target[len(s.target)-1]
+	}
+
+	switch {
+	case s.done:
+
+	case from && !s.have:
+		// hold the value of the from location, removing it for a move
+		if s.op.Op == "copy" {
+			if err := writeKey(); err != nil {
+				return err
+			}
+		}
+		raw, err := d.skip(true)
+		if err != nil {
+			return err
+		}
+		s.value, s.have = append([]byte(nil), raw...), true
+		if s.op.Op == "copy" {
+			return e.writeRaw(raw)
+		}
+		if inArray {
+			s.shiftTarget(d.path.top().index)
+		}
+		return nil
+
+	case target:
+		switch s.op.Op {
+		case "remove":
+			s.done = true
+			_, err := d.skip(false)
+			return err
+
+		case "replace":
+			s.done = true
+			if err := writeKey(); err != nil {
+				return err
+			}
+			if _, err := d.skip(false); err != nil {
+				return err
+			}
+			return e.writeRaw(s.value)
+
+		case "test":
+			s.done = true
+			if err := writeKey(); err != nil {
+				return err
+			}
+			raw, err := d.skip(true)
+			if err != nil {
+				return err
+			}
+			if equal, err := jsonEqual(raw, s.value); err != nil {
+				return err
+			} else if !equal {
+				return s.error(ErrPatchTestFailed)
+			}
+			return e.writeRaw(raw)
+		}
+
+		// add, move or copy
+		if inArray {
+			// insert the value before the existing element
+			if !s.have {
+				return s.error(ErrPatchUnsupported)
+			}
+			s.done = true
+			if err := e.writeRaw(s.value); err != nil {
+				return err
+			}
+			break
+		}
+		if !s.have && fromWithin {
+			// the from location is within the value being replaced
+			raw, err := d.skip(true)
+			if err != nil {
+				return err
+			}
+			v, ok, err := extractPointer(raw, s.from[len(s.target):])
+			if err != nil {
+				return err
+			} else if !ok {
+				return s.error(ErrPatchPathNotFound)
+			}
+			s.value, s.have = v, true
+		} else if _, err := d.skip(false); err != nil {
+			return err
+		}
+		if !s.have {
+			// the existing member is removed, and the value is added when the object ends
+			return nil
+		}
+		s.done = true
+		if key {
+			if err := e.WriteKey(last); err != nil {
+				return err
+			}
+		}
+		return e.writeRaw(s.value)
+
+	case within:
+		if err := writeKey(); err != nil {
+			return err
+		}
+		return copyToken(d, e)
+	}
+
+	if err := writeKey(); err != nil {
+		return err
+	}
+	raw, err := d.skip(true)
+	if err != nil {
+		return err
+	}
+	return e.writeRaw(raw)
+}
+
+// shiftTarget accounts for the removal of the element at index from the array containing the from location. A
+// target within a later element of the same array is one element further on in the input.
+func (s *patchStage) shiftTarget(index int) {
+	k := len(s.from) - 1
+	if len(s.target) <= k || !hasPointerPrefix(s.target, s.from[:k]) {
+		return
+	}
+	n, err := strconv.Atoi(s.target[k])
+	if err != nil || n < index {
+		return
+	}
+	s.target = append([]string(nil), s.target...)
+	s.target[k] = strconv.Itoa(n + 1)
+}
+
+// end applies the operation, if it has not yet been applied, before the end of the current object or array.
+func (s *patchStage) end(d *Decoder, e *Encoder) error {
+	// the top frame of the path holds the position within the object or array, so the path of the object or array
+	// itself excludes it
+	n := len(d.path)
+	if s.done || len(s.target) != n || !pointerEqual(s.target[:n-1], d.path[:n-1]) {
+		return nil
+	}
+	if s.op.Op == "remove" || s.op.Op == "replace" || s.op.Op == "test" {
+		return s.error(ErrPatchPathNotFound)
+	}
+
+	// add, move or copy to the end of the object or array
+	last := s.target[len(s.target)-1]
+	top := d.path.top()
+	if top.array && last != "-" && last != strconv.Itoa(top.index+1) {
+		return s.error(ErrPatchPathNotFound)
+	}
+	if !s.have {
+		return s.error(ErrPatchUnsupported)
+	}
+	s.done = true
+	if !top.array {
+		if err := e.WriteKey(last); err != nil {
+			return err
+		}
+	}
+	return e.writeRaw(s.value)
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into its unescaped reference tokens.
+func parsePointer(ptr string) ([]string, error) {
+	if ptr == "" {
+		return []string{}, nil
+	}
+	if ptr[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON Pointer %q", ptr)
+	}
+	tokens := strings.Split(ptr[1:], "/")
+	for i, t := range tokens {
+		if strings.IndexByte(t, '~') < 0 {
+			continue
+		}
+		for j := 0; j < len(t); j++ {
+			if t[j] == '~' && (j+1 == len(t) || t[j+1] != '0' && t[j+1] != '1') {
+				return nil, fmt.Errorf("invalid escape in JSON Pointer %q", ptr)
+			}
+		}
+		tokens[i] = pointerUnescaper.Replace(t)
+	}
+	return tokens, nil
+}
+
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// pointerPrefix reports whether the path s is a prefix of, or equal to, the location referenced by tokens. An array
+// index matches a reference token only in its canonical decimal form.
+func pointerPrefix(tokens []string, s pathStack) bool {
+	if len(s) > len(tokens) {
+		return false
+	}
+	for i := range s {
+		f := &s[i]
+		if f.array {
+			if tokens[i] != strconv.Itoa(f.index) {
+				return false
+			}
+		} else if string(f.key) != tokens[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pointerEqual reports whether the path s is the location referenced by tokens.
+func pointerEqual(tokens []string, s pathStack) bool {
+	return len(s) == len(tokens) && pointerPrefix(tokens, s)
+}
+
+// hasPointerPrefix reports whether the reference tokens begin with prefix.
+func hasPointerPrefix(tokens, prefix []string) bool {
+	if len(prefix) > len(tokens) {
+		return false
+	}
+	for i := range prefix {
+		if tokens[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// extractPointer returns the value at the location referenced by tokens within the JSON value raw.
+func extractPointer(raw []byte, tokens []string) ([]byte, bool, error) {
+	d := NewDecoder(bytes.NewReader(raw))
+	if len(tokens) == 0 {
+		v, err := d.skip(true)
+		return append([]byte(nil), v...), err == nil, err
+	}
+	for {
+		if d.context == objValue || d.context == arrValue && d.More() {
+			inArray := d.context == arrValue
+			if inArray {
+				d.path.incTop()
+			}
+			found := pointerEqual(tokens, d.path)
+			prefix := pointerPrefix(tokens, d.path)
+			if inArray {
+				d.path.top().index--
+			}
+			if found {
+				v, err := d.skip(true)
+				return append([]byte(nil), v...), err == nil, err
+			}
+			if !prefix {
+				if _, err := d.skip(false); err != nil {
+					return nil, false, err
+				}
+				continue
+			}
+		}
+		if _, err := d.next(); err == io.EOF {
+			return nil, false, nil
+		} else if err != nil {
+			return nil, false, err
+		}
+	}
+}
+
+// jsonEqual reports whether two JSON values are equal. Numbers are compared by their exact values, so 1.0 is equal
+// to 1 but large integers that round to the same float64 are not equal.
+func jsonEqual(a, b []byte) (bool, error) {
+	va, err := decodeSchemaValue(a)
+	if err != nil {
+		return false, err
+	}
+	vb, err := decodeSchemaValue(b)
+	if err != nil {
+		return false, err
+	}
+	return valuesEqual(va, vb), nil
+}
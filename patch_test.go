@@ -0,0 +1,175 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func applyPatch(doc, patch string) (string, error) {
+	var p Patch
+	if err := json.Unmarshal([]byte(patch), &p); err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	err := p.Apply(NewDecoder(strings.NewReader(doc)), NewEncoder(&out))
+	return out.String(), err
+}
+
+func TestPatchApply(t *testing.T) {
+
+	tests := []struct {
+		doc   string
+		patch string
+		out   string
+	}{
+		// examples from RFC 6902 appendix A
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"foo":"bar","baz":"qux"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			`{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, `{"foo":"bar","baz":"qux"}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"foo":null}`, `[{"op":"test","path":"/foo","value":null}]`, `{"foo":null}`},
+		{`{"n":1e9999999}`, `[{"op":"test","path":"/n","value":0.1e10000000}]`, `{"n":1e9999999}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10},{"op":"replace","path":"/~1","value":1}]`, `{"/":1,"~1":10}`},
+
+		// sequences of operations, each applying to the result of the last
+		{`{"a":1}`, `[{"op":"add","path":"/b","value":2},{"op":"replace","path":"/b","value":3},{"op":"remove","path":"/a"}]`, `{"b":3}`},
+		{`{"a":{"b":1},"c":[]}`, `[{"op":"copy","from":"/a","path":"/c/0"},{"op":"replace","path":"/c/0/b","value":2}]`,
+			`{"a":{"b":1},"c":[{"b":2}]}`},
+		{`{"a":{"x":1},"b":[1,2]}`,
+			`[{"op":"replace","path":"/a/x","value":2},{"op":"test","path":"/b/1","value":2},{"op":"replace","path":"/b/0","value":0},`+
+				`{"op":"test","path":"/a/x","value":2},{"op":"replace","path":"/a","value":[]}]`,
+			`{"a":[],"b":[0,2]}`},
+
+		// the target of a move is located after the from location is removed
+		{`["a","b","c"]`, `[{"op":"move","from":"/0","path":"/1"}]`, `["b","a","c"]`},
+		{`["a","b","c"]`, `[{"op":"move","from":"/0","path":"/2"}]`, `["b","c","a"]`},
+		{`[["a","b"],["c"]]`, `[{"op":"move","from":"/0/0","path":"/1/0"}]`, `[["b"],["a","c"]]`},
+
+		// add replaces an existing member in place
+		{`{"a":1,"b":2}`, `[{"op":"add","path":"/a","value":[3]}]`, `{"a":[3],"b":2}`},
+		// a value can be moved up from within the value it replaces
+		{`{"a":{"b":{"c":1}},"d":2}`, `[{"op":"move","from":"/a/b","path":"/a"}]`, `{"a":{"c":1},"d":2}`},
+		// the root can be replaced
+		{`{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+		// moving a value to itself has no effect
+		{`{"a":1}`, `[{"op":"move","from":"/a","path":"/a"}]`, `{"a":1}`},
+		{`{"a":1}`, `[]`, `{"a":1}`},
+	}
+
+	for _, tst := range tests {
+		out, err := applyPatch(tst.doc, tst.patch)
+		if assert.NoError(t, err, tst.patch) {
+			assert.Equal(t, tst.out+"\n", out, tst.patch)
+		}
+	}
+}
+
+func TestPatchApplyStream(t *testing.T) {
+
+	out, err := applyPatch(`{"a":1} {"a":2}`, `[{"op":"copy","from":"/a","path":"/b"},{"op":"remove","path":"/a"}]`)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"b\":1}\n{\"b\":2}\n", out)
+
+	// operations applied in the same pass apply to each value
+	_, err = applyPatch(`{"a":[1],"b":1} {"a":[2],"b":2}`,
+		`[{"op":"replace","path":"/a/0","value":0},{"op":"test","path":"/b","value":2}]`)
+	assert.True(t, errors.Is(err, ErrPatchTestFailed), "%v", err)
+	out, err = applyPatch(`{"a":[1],"b":1} {"a":[2],"b":1}`,
+		`[{"op":"replace","path":"/a/0","value":0},{"op":"test","path":"/b","value":1}]`)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"a\":[0],\"b\":1}\n{\"a\":[0],\"b\":1}\n", out)
+}
+
+func TestPatchErrors(t *testing.T) {
+
+	tests := []struct {
+		doc   string
+		patch string
+		index int
+		err   error
+	}{
+		{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, 0, ErrPatchTestFailed},
+		{`{"a":[1]}`, `[{"op":"add","path":"/b","value":1},{"op":"test","path":"/a/0","value":2}]`, 1, ErrPatchTestFailed},
+		{`{"id":9007199254740993}`, `[{"op":"test","path":"/id","value":9007199254740992}]`, 0, ErrPatchTestFailed},
+		{`{"n":1e9999999}`, `[{"op":"test","path":"/n","value":1e9999998}]`, 0, ErrPatchTestFailed},
+		{`{"a":1,"b":2}`, `[{"op":"replace","path":"/a","value":3},{"op":"test","path":"/b","value":3}]`, 1, ErrPatchTestFailed},
+		{`{"a":1,"b":2}`, `[{"op":"test","path":"/b","value":2},{"op":"replace","path":"/c","value":1}]`, 1, ErrPatchPathNotFound},
+		{`[{"a":1}]`, `[{"op":"replace","path":"/0/a","value":2},{"op":"replace","path":"/1/a","value":2}]`, 1, ErrPatchPathNotFound},
+		{`{"baz":"qux"}`, `[{"op":"remove","path":"/foo"}]`, 0, ErrPatchPathNotFound},
+		{`{"baz":"qux"}`, `[{"op":"replace","path":"/foo/bar","value":1}]`, 0, ErrPatchPathNotFound},
+		{`{"baz":"qux"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, 0, ErrPatchPathNotFound},
+		{`{"baz":"qux"}`, `[{"op":"add","path":"/foo/bar","value":"qux"}]`, 0, ErrPatchPathNotFound},
+		{`{"a":[1,2]}`, `[{"op":"add","path":"/a/3","value":3}]`, 0, ErrPatchPathNotFound},
+		{`{"a":[1,2]}`, `[{"op":"remove","path":"/a/-"}]`, 0, ErrPatchPathNotFound},
+		{`{"a":1}`, `[{"op":"move","from":"/b","path":"/c"}]`, 0, ErrPatchUnsupported},
+		{`{"a":{},"b":1}`, `[{"op":"move","from":"/b","path":"/a/c"}]`, 0, ErrPatchUnsupported},
+		{`{"a":[0,1]}`, `[{"op":"move","from":"/a/1","path":"/a/0"}]`, 0, ErrPatchUnsupported},
+	}
+
+	for _, tst := range tests {
+		_, err := applyPatch(tst.doc, tst.patch)
+		var perr *PatchError
+		if assert.True(t, errors.As(err, &perr), "%s: %v", tst.patch, err) {
+			assert.Equal(t, tst.index, perr.Index, tst.patch)
+			assert.True(t, errors.Is(err, tst.err), "%s: %v", tst.patch, err)
+		}
+	}
+}
+
+func TestPatchInvalid(t *testing.T) {
+
+	for _, patch := range []string{
+		`[{"op":"add","path":"/a"}]`,
+		`[{"op":"add","path":"a","value":1}]`,
+		`[{"op":"add","path":"/a~2","value":1}]`,
+		`[{"op":"add","path":"/-/a","value":1}]`,
+		`[{"op":"remove","path":""}]`,
+		`[{"op":"move","from":"/a","path":"/a/b"}]`,
+		`[{"op":"frob","path":"/a"}]`,
+	} {
+		_, err := applyPatch(`{"a":1}`, patch)
+		assert.IsType(t, &PatchError{}, err, patch)
+	}
+}
+
+func TestPatchSyntaxError(t *testing.T) {
+
+	_, err := applyPatch(`{"a":[1,}`, `[{"op":"add","path":"/b","value":1},{"op":"add","path":"/c","value":1}]`)
+	assert.IsType(t, &json.SyntaxError{}, err)
+}
+
+func TestParsePointer(t *testing.T) {
+
+	tests := []struct {
+		in  string
+		out []string
+	}{
+		{"", []string{}},
+		{"/", []string{""}},
+		{"/a/0", []string{"a", "0"}},
+		{"/a~1b/m~0n/~01", []string{"a/b", "m~n", "~1"}},
+	}
+	for _, tst := range tests {
+		out, err := parsePointer(tst.in)
+		require.NoError(t, err, tst.in)
+		assert.Equal(t, tst.out, out, tst.in)
+	}
+}
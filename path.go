@@ -1,7 +1,11 @@
 // Extends the Go runtime's json.Decoder enabling navigation of a stream of json tokens.
 package jsonpath
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 type jsonContext int
 
@@ -19,15 +23,9 @@ const AnyIndex = -2
 // each integer specifies an index into a JSON array.
 type JsonPath []interface{}
 
-func (p *JsonPath) push(n interface{}) { *p = append(*p, n) }
-func (p *JsonPath) pop()               { *p = (*p)[:len(*p)-1] }
-
 // increment the index at the top of the stack (must be an array index)
 func (p *JsonPath) incTop() { (*p)[len(*p)-1] = (*p)[len(*p)-1].(int) + 1 }
 
-// name the key at the top of the stack (must be an object key)
-func (p *JsonPath) nameTop(n string) { (*p)[len(*p)-1] = n }
-
 // infer the context from the item at the top of the stack
 func (p *JsonPath) inferContext() jsonContext {
 	if len(*p) == 0 {
@@ -65,3 +63,230 @@ func (p *JsonPath) HasPrefix(o JsonPath) bool {
 	}
 	return true
 }
+
+// ParsePath parses a path expression such as $.a.b[0].c into a JsonPath. An expression consists of an optional
+// leading $ followed by any number of
+//
+//   - .key, where key does not contain '.' or '['
+//   - ['key'] or ["key"], where key may contain any character and a quote or backslash is escaped with a backslash
+//   - [n], an array index
+//   - [*], which matches any array index and is parsed as AnyIndex
+//
+// The first key may be written without a leading '.' when the expression does not begin with $.
+func ParsePath(expr string) (JsonPath, error) {
+	orig, implied := expr, 0
+	p := JsonPath{}
+	i := 0
+	if strings.HasPrefix(expr, "$") {
+		i = 1
+	} else if expr != "" && expr[0] != '.' && expr[0] != '[' {
+		expr, implied = "."+expr, 1
+	}
+
+	invalid := func(i int) (JsonPath, error) {
+		return nil, fmt.Errorf("jsonpath: invalid path %q at offset %d", orig, i-implied)
+	}
+	for i < len(expr) {
+		switch expr[i] {
+		case '.':
+			j := i + 1
+			for j < len(expr) && expr[j] != '.' && expr[j] != '[' {
+				j++
+			}
+			if j == i+1 {
+				return invalid(i)
+			}
+			p = append(p, expr[i+1:j])
+			i = j
+
+		case '[':
+			if i+1 == len(expr) {
+				return invalid(i)
+			}
+			if q := expr[i+1]; q == '\'' || q == '"' {
+				var key []byte
+				j := i + 2
+				for ; j < len(expr) && expr[j] != q; j++ {
+					if expr[j] == '\\' {
+						j++
+						if j == len(expr) {
+							return invalid(i)
+						}
+					}
+					key = append(key, expr[j])
+				}
+				if j+1 >= len(expr) || expr[j+1] != ']' {
+					return invalid(i)
+				}
+				p = append(p, string(key))
+				i = j + 2
+				continue
+			}
+			j := strings.IndexByte(expr[i:], ']')
+			if j < 0 {
+				return invalid(i)
+			}
+			j += i
+			if expr[i+1:j] == "*" {
+				p = append(p, AnyIndex)
+			} else if n, err := strconv.Atoi(expr[i+1 : j]); err == nil && n >= 0 && expr[i+1] != '+' {
+				p = append(p, n)
+			} else {
+				return invalid(i)
+			}
+			i = j + 1
+
+		default:
+			return invalid(i)
+		}
+	}
+	return p, nil
+}
+
+// FormatPath formats p as a path expression that ParsePath parses back to p, such as $.a.b[0].c. Keys that are not
+// made up of letters, digits and underscores, or that begin with a digit, are written in the form ["key"].
+func FormatPath(p JsonPath) string {
+	var b strings.Builder
+	b.WriteByte('$')
+	for _, e := range p {
+		switch e := e.(type) {
+		case string:
+			if isIdentifier(e) {
+				b.WriteByte('.')
+				b.WriteString(e)
+				continue
+			}
+			b.WriteString(`["`)
+			for i := 0; i < len(e); i++ {
+				if e[i] == '"' || e[i] == '\\' {
+					b.WriteByte('\\')
+				}
+				b.WriteByte(e[i])
+			}
+			b.WriteString(`"]`)
+		case int:
+			if e == AnyIndex {
+				b.WriteString("[*]")
+			} else {
+				b.WriteString("[" + strconv.Itoa(e) + "]")
+			}
+		default:
+			panic(fmt.Sprintf("Invalid path element type %T", e))
+		}
+	}
+	return b.String()
+}
+
+// isIdentifier reports whether the key can be written as .key in a path expression.
+func isIdentifier(key string) bool {
+	if key == "" || key[0] >= '0' && key[0] <= '9' {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// pathFrame is one level of the path maintained by the Decoder. Object keys are held as bytes so that the path can
+// be updated as tokens are read without allocating.
+type pathFrame struct {
+	key   []byte // object key, when array is false
+	index int    // array index, when array is true
+	array bool
+}
+
+// pathStack is the Decoder's internal representation of a JsonPath.
+type pathStack []pathFrame
+
+// push adds a new level for an object (with an empty key) or an array (with index -1). Frames beyond the length of
+// the stack are reused so that their key buffers are retained.
+func (s *pathStack) push(array bool) {
+	if len(*s) < cap(*s) {
+		*s = (*s)[:len(*s)+1]
+	} else {
+		*s = append(*s, pathFrame{})
+	}
+	f := &(*s)[len(*s)-1]
+	f.key = f.key[:0]
+	f.index = -1
+	f.array = array
+}
+
+func (s *pathStack) pop() { *s = (*s)[:len(*s)-1] }
+
+// increment the index at the top of the stack (must be an array index)
+func (s pathStack) incTop() { s[len(s)-1].index++ }
+
+// top returns the frame at the top of the stack
+func (s pathStack) top() *pathFrame { return &s[len(s)-1] }
+
+// infer the context from the item at the top of the stack
+func (s pathStack) inferContext() jsonContext {
+	if len(s) == 0 {
+		return none
+	}
+	if s[len(s)-1].array {
+		return arrValue
+	}
+	return objKey
+}
+
+// matches reports whether the frame matches the pattern element e, which is a string, an int or AnyIndex.
+func (f *pathFrame) matches(e interface{}) bool {
+	switch e := e.(type) {
+	case string:
+		return !f.array && string(f.key) == e
+	case int:
+		return f.array && (f.index == e || e == AnyIndex)
+	}
+	return false
+}
+
+// element returns the path element at level i as a string or an int.
+func (s pathStack) element(i int) interface{} {
+	if s[i].array {
+		return s[i].index
+	}
+	return string(s[i].key)
+}
+
+// jsonPath returns a copy of the stack as a JsonPath.
+func (s pathStack) jsonPath() JsonPath {
+	p := make(JsonPath, len(s))
+	for i := range s {
+		p[i] = s.element(i)
+	}
+	return p
+}
+
+// set replaces the contents of the stack with the path p.
+func (s *pathStack) set(p JsonPath) {
+	*s = (*s)[:0]
+	for _, e := range p {
+		switch e := e.(type) {
+		case string:
+			s.push(false)
+			s.top().key = append(s.top().key, e...)
+		case int:
+			s.push(true)
+			s.top().index = e
+		}
+	}
+}
+
+// equal tests for equality with a JsonPath without allocating.
+func (s pathStack) equal(p JsonPath) bool {
+	if len(s) != len(p) {
+		return false
+	}
+	for i, e := range p {
+		if e == AnyIndex || !s[i].matches(e) {
+			return false
+		}
+	}
+	return true
+}
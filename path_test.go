@@ -130,3 +130,53 @@ func TestTokensAndPaths(t *testing.T) {
 		}
 	}
 }
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		expr string
+		path JsonPath
+	}{
+		{"$", JsonPath{}},
+		{"", JsonPath{}},
+		{"$.a.b[0].c", JsonPath{"a", "b", 0, "c"}},
+		{"a.b", JsonPath{"a", "b"}},
+		{"$[2][*]", JsonPath{2, AnyIndex}},
+		{"[1].a", JsonPath{1, "a"}},
+		{`$['a.b']["c[d]"]`, JsonPath{"a.b", "c[d]"}},
+		{`$['it\'s']["\\"]`, JsonPath{"it's", `\`}},
+	}
+	for _, tst := range tests {
+		p, err := ParsePath(tst.expr)
+		if err != nil {
+			t.Errorf("%q: %v", tst.expr, err)
+		} else if !p.Equal(tst.path) {
+			t.Errorf("%q: exp: %v but was: %v", tst.expr, tst.path, p)
+		}
+	}
+
+	for _, expr := range []string{"$.", "$..a", "$a", "a[", "$[x]", "$[-1]", "$[+1]", "$['a]", "$['a'", "$[1"} {
+		if _, err := ParsePath(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
+
+func TestFormatPath(t *testing.T) {
+	tests := []struct {
+		path JsonPath
+		expr string
+	}{
+		{JsonPath{}, "$"},
+		{JsonPath{"a", "b", 0, "c"}, "$.a.b[0].c"},
+		{JsonPath{"a_1", AnyIndex}, "$.a_1[*]"},
+		{JsonPath{"a.b", "", "1a", `q"\`}, `$["a.b"][""]["1a"]["q\"\\"]`},
+	}
+	for _, tst := range tests {
+		if expr := FormatPath(tst.path); expr != tst.expr {
+			t.Errorf("%v: exp: %q but was: %q", tst.path, tst.expr, expr)
+		}
+		if p, err := ParsePath(tst.expr); err != nil || !p.Equal(tst.path) {
+			t.Errorf("%q: parsed as %v, %v", tst.expr, p, err)
+		}
+	}
+}
@@ -1,39 +1,19 @@
 package jsonpath
 
+import "sync/atomic"
+
 // pathNode is used to construct a trie of paths to be matched
 type pathNode struct {
 	matchOn    interface{} // string, or integer
-	childNodes []pathNode
+	childNodes []*pathNode
 	action     DecodeAction
 }
 
-// match climbs the trie to find a node that matches the given JSON path.
-func (n *pathNode) match(path JsonPath) *pathNode {
-	var node *pathNode = n
-	for _, ps := range path {
-		found := false
-		for i, n := range node.childNodes {
-			if n.matchOn == ps {
-				node = &node.childNodes[i]
-				found = true
-				break
-			} else if _, ok := ps.(int); ok && n.matchOn == AnyIndex {
-				node = &node.childNodes[i]
-				found = true
-				break
-			}
-		}
-		if !found {
-			return nil
-		}
-	}
-	return node
-}
-
 // PathActions represents a collection of DecodeAction functions that should be called at certain path positions
 // when scanning the JSON stream. PathActions can be created once and used many times in one or more JSON streams.
 type PathActions struct {
-	node pathNode
+	node     pathNode
+	compiled atomic.Value // the *PathMatcher returned by Compile, or nil after Add
 }
 
 // DecodeAction handlers are called by the Decoder when scanning objects. See PathActions.Add for more detail.
@@ -42,20 +22,186 @@ type DecodeAction func(d *Decoder) error
 // Add specifies an action to call on the Decoder when the specified path is encountered.
 func (je *PathActions) Add(action DecodeAction, path ...interface{}) {
 
+	je.insert(path).action = action
+}
+
+// insert returns the trie node for path, adding it if necessary, and discards the compiled matcher.
+func (je *PathActions) insert(path []interface{}) *pathNode {
+	je.compiled.Store((*PathMatcher)(nil))
+
 	var node *pathNode = &je.node
 	for _, ps := range path {
 		found := false
-		for i, n := range node.childNodes {
+		for _, n := range node.childNodes {
 			if n.matchOn == ps {
-				node = &node.childNodes[i]
+				node = n
 				found = true
 				break
 			}
 		}
 		if !found {
-			node.childNodes = append(node.childNodes, pathNode{matchOn: ps})
-			node = &node.childNodes[len(node.childNodes)-1]
+			n := &pathNode{matchOn: ps}
+			node.childNodes = append(node.childNodes, n)
+			node = n
 		}
 	}
-	node.action = action
+	return node
+}
+
+// Compile returns a PathMatcher for the actions that have been added. The PathMatcher is immutable and safe for
+// concurrent use by multiple goroutines; actions added later are not included in it. Compile caches its result
+// until the next call to Add, so Decoder.Scan does not compile the same actions again for each call.
+func (je *PathActions) Compile() *PathMatcher {
+	if m, _ := je.compiled.Load().(*PathMatcher); m != nil {
+		return m
+	}
+	m := &PathMatcher{root: compileNode(&je.node)}
+	je.compiled.Store(m)
+	return m
+}
+
+// PathMatcher is the compiled form of PathActions. See PathActions.Compile and Decoder.ScanMatcher.
+//
+// Each level of a path is matched with a single lookup. An array index matches both the patterns with that index
+// and the patterns with AnyIndex; where both have an action for the same path, the action of the pattern with the
+// index is called.
+type PathMatcher struct {
+	root *matchNode
+}
+
+// matchNode is a node of the compiled trie, with children indexed by object key and array index.
+type matchNode struct {
+	keys     map[string]*matchNode
+	indexes  map[int]*matchNode
+	anyIndex *matchNode
+	action   DecodeAction
+	sources  []*pathNode // the trie nodes compiled into this node, in order of preference
+}
+
+// compileNode compiles the trie node n. The subtree compiled for its AnyIndex child is shared by the children for
+// explicit indexes, which are merged with it.
+func compileNode(n *pathNode) *matchNode {
+	m := &matchNode{action: n.action, sources: []*pathNode{n}}
+	for _, c := range n.childNodes {
+		if c.matchOn == AnyIndex {
+			m.anyIndex = compileNode(c)
+		}
+	}
+	for _, c := range n.childNodes {
+		switch e := c.matchOn.(type) {
+		case string:
+			if m.keys == nil {
+				m.keys = map[string]*matchNode{}
+			}
+			m.keys[e] = compileNode(c)
+		case int:
+			if e == AnyIndex {
+				continue
+			}
+			if m.indexes == nil {
+				m.indexes = map[int]*matchNode{}
+			}
+			// an index also matches the patterns with AnyIndex, which are merged after those with the index
+			m.indexes[e] = mergeNodes(compileNode(c), m.anyIndex)
+		}
+	}
+	return m
+}
+
+// mergeNodes returns a node matching the paths of both a and b, preferring the actions of a. Compiled nodes are
+// immutable, so the parts of a and b that do not overlap are shared rather than copied.
+func mergeNodes(a, b *matchNode) *matchNode {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	m := &matchNode{action: a.action, sources: append(append([]*pathNode(nil), a.sources...), b.sources...)}
+	if m.action == nil {
+		m.action = b.action
+	}
+	if a.keys != nil || b.keys != nil {
+		m.keys = make(map[string]*matchNode, len(a.keys)+len(b.keys))
+		for k, c := range a.keys {
+			m.keys[k] = mergeNodes(c, b.keys[k])
+		}
+		for k, c := range b.keys {
+			if _, ok := a.keys[k]; !ok {
+				m.keys[k] = c
+			}
+		}
+	}
+	if a.indexes != nil || b.indexes != nil {
+		m.indexes = make(map[int]*matchNode, len(a.indexes)+len(b.indexes))
+		for i, c := range a.indexes {
+			m.indexes[i] = mergeNodes(c, b.childAt(i))
+		}
+		for i, c := range b.indexes {
+			if _, ok := a.indexes[i]; !ok {
+				m.indexes[i] = mergeNodes(a.anyIndex, c)
+			}
+		}
+	}
+	m.anyIndex = mergeNodes(a.anyIndex, b.anyIndex)
+	return m
+}
+
+// childAt returns the node matching the array index i, or nil if there is none.
+func (n *matchNode) childAt(i int) *matchNode {
+	if c, ok := n.indexes[i]; ok {
+		return c
+	}
+	return n.anyIndex
+}
+
+// child returns the node matching the path level f, or nil if there is none.
+func (n *matchNode) child(f *pathFrame) *matchNode {
+	if n == nil {
+		return nil
+	}
+	if !f.array {
+		return n.keys[string(f.key)]
+	}
+	return n.childAt(f.index)
+}
+
+// leaf reports whether n has no children, so that no path below it can match.
+func (n *matchNode) leaf() bool {
+	return n.keys == nil && n.indexes == nil && n.anyIndex == nil
+}
+
+// matchState holds the node matching each level of a path relative to the root of a scan. nodes[i] matches the
+// first i levels, so nodes[0] is the root of the trie.
+type matchState struct {
+	m     *PathMatcher
+	nodes []*matchNode
+}
+
+// reset matches the relative path rel from the root.
+func (s *matchState) reset(rel pathStack) *matchNode {
+	s.nodes = append(s.nodes[:0], s.m.root)
+	return s.matchFrom(1, rel)
+}
+
+// update matches the relative path rel after a single token has been read, re-matching only the levels the token
+// may have changed: the top level, and its parent if the token opened an object or array.
+func (s *matchState) update(rel pathStack, opened bool) *matchNode {
+	from := len(rel)
+	if opened {
+		from--
+	}
+	if from < 1 || from > len(s.nodes) {
+		return s.reset(rel)
+	}
+	return s.matchFrom(from, rel)
+}
+
+// matchFrom re-matches the levels of rel from the given level down, retaining the nodes above it.
+func (s *matchState) matchFrom(from int, rel pathStack) *matchNode {
+	s.nodes = s.nodes[:from]
+	for i := from - 1; i < len(rel); i++ {
+		s.nodes = append(s.nodes, s.nodes[i].child(&rel[i]))
+	}
+	return s.nodes[len(rel)]
 }
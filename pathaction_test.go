@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -561,3 +562,119 @@ func TestPathActionNested(t *testing.T) {
 
 	assert.Equal(t, []string{"1948-1965", "1964-1969", "1975-1985"}, outs)
 }
+
+func TestPathActionsCompile(t *testing.T) {
+
+	actions := &PathActions{}
+	actions.Add(func(d *Decoder) error { return nil }, "a")
+
+	m := actions.Compile()
+	assert.True(t, m == actions.Compile(), "Compile should cache its result")
+
+	var b int
+	actions.Add(func(d *Decoder) error { return d.Decode(&b) }, "b")
+	m2 := actions.Compile()
+	assert.False(t, m == m2, "Add should invalidate the compiled matcher")
+
+	// the earlier matcher does not include the later action
+	_, err := NewDecoder(bytes.NewBufferString(`{"a":1,"b":2}`)).ScanMatcher(m)
+	require.NoError(t, err)
+	assert.Equal(t, 0, b)
+
+	_, err = NewDecoder(bytes.NewBufferString(`{"a":1,"b":2}`)).ScanMatcher(m2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, b)
+}
+
+func TestPathMatcherIndexPreferred(t *testing.T) {
+
+	var any, exact []int
+	actions := &PathActions{}
+	actions.Add(func(d *Decoder) error {
+		var v int
+		err := d.Decode(&v)
+		any = append(any, v)
+		return err
+	}, "a", AnyIndex, "v")
+	actions.Add(func(d *Decoder) error {
+		var v int
+		err := d.Decode(&v)
+		exact = append(exact, v)
+		return err
+	}, "a", 1, "v")
+
+	_, err := NewDecoder(bytes.NewBufferString(`{"a":[{"v":10},{"v":11},{"v":12}]}`)).Scan(actions)
+	require.NoError(t, err)
+	assert.Equal(t, []int{11}, exact)
+	assert.Equal(t, []int{10, 12}, any)
+
+	// patterns with AnyIndex still match an index that has patterns of its own
+	var prices, qtys []int
+	actions = &PathActions{}
+	actions.Add(func(d *Decoder) error {
+		var v int
+		err := d.Decode(&v)
+		prices = append(prices, v)
+		return err
+	}, "lines", AnyIndex, "price")
+	actions.Add(func(d *Decoder) error {
+		var v int
+		err := d.Decode(&v)
+		qtys = append(qtys, v)
+		return err
+	}, "lines", 0, "qty")
+
+	_, err = NewDecoder(bytes.NewBufferString(`{"lines":[{"qty":2,"price":1},{"qty":4,"price":3}]}`)).Scan(actions)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 3}, prices)
+	assert.Equal(t, []int{2}, qtys)
+}
+
+func TestPathMatcherConcurrent(t *testing.T) {
+
+	var mu sync.Mutex
+	sum := 0
+	actions := &PathActions{}
+	actions.Add(func(d *Decoder) error {
+		var v int
+		err := d.Decode(&v)
+		mu.Lock()
+		sum += v
+		mu.Unlock()
+		return err
+	}, "items", AnyIndex, "v")
+	m := actions.Compile()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			j := fmt.Sprintf(`{"items":[{"v":%d},{"v":%d},{"w":true}]}`, i, i+1)
+			_, errs[i] = NewDecoder(bytes.NewBufferString(j)).ScanMatcher(m)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	// the sum of i and i+1 for i in 0..7
+	assert.Equal(t, 64, sum)
+}
+
+func TestPathActionExactIndex(t *testing.T) {
+
+	var got []int
+	actions := &PathActions{}
+	actions.Add(func(d *Decoder) error {
+		var v int
+		err := d.Decode(&v)
+		got = append(got, v)
+		return err
+	}, "a", 1)
+
+	_, err := NewDecoder(bytes.NewBufferString(`{"a":[10,11,12],"b":[20]}`)).Scan(actions)
+	require.NoError(t, err)
+	assert.Equal(t, []int{11}, got)
+}
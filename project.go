@@ -0,0 +1,210 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Projection copies the parts of a JSON stream at a set of selected paths from a Decoder to an Encoder, producing
+// a smaller document with the same structure. Paths are selected in the same way as PathActions, and AnyIndex may
+// be used in a path to select every element of an array.
+//
+// A selected value is copied completely, as it appears in the input. The objects and arrays that enclose selected
+// values are written with only the keys and elements that lead to them; those that enclose no selected value are
+// omitted. Each top-level object or array in the stream produces one output value, which is empty if nothing in it
+// is selected.
+//
+// A Projection can be used many times, including concurrently.
+type Projection struct {
+	// PreserveIndices causes dropped array elements that precede a kept element to be written as null, so that each
+	// kept element has the same index in the output as in the input. By default dropped elements are removed and
+	// the elements that follow them move to lower indices.
+	PreserveIndices bool
+
+	paths    PathActions
+	selected map[*pathNode]bool // the trie nodes of paths that are selected
+}
+
+// projectLevel is an object or array that encloses the current position in the input.
+type projectLevel struct {
+	delim   json.Delim // the opening delimiter
+	key     string     // the key of the object or array within its parent object
+	index   int        // the index of the object or array within its parent array
+	written bool       // the opening delimiter has been written
+}
+
+// Add selects the value at path.
+func (p *Projection) Add(path ...interface{}) {
+	if p.selected == nil {
+		p.selected = map[*pathNode]bool{}
+	}
+	p.selected[p.paths.insert(path)] = true
+}
+
+// isSelected reports whether the compiled node n matches a selected path.
+func (p *Projection) isSelected(n *matchNode) bool {
+	for _, src := range n.sources {
+		if p.selected[src] {
+			return true
+		}
+	}
+	return false
+}
+
+// Project copies the selected parts of the remainder of the JSON stream from d to e. Paths are matched from the
+// root of the stream, so Project is normally called before any tokens have been read from d. Project returns nil
+// when the end of the stream is reached.
+func (p *Projection) Project(d *Decoder, e *Encoder) error {
+
+	state := matchState{m: p.paths.Compile()}
+	var levels []projectLevel
+	for {
+		var node *matchNode
+		switch d.context {
+		case objKey, arrValue:
+			if !d.More() {
+				if _, err := d.next(); err != nil {
+					return err
+				}
+				if len(levels) == 1 {
+					// a top-level object or array is written even if nothing in it is selected
+					if err := p.open(e, levels); err != nil {
+						return err
+					}
+				}
+				top := levels[len(levels)-1]
+				levels = levels[:len(levels)-1]
+				if top.written {
+					end := json.Delim('}')
+					if top.delim == '[' {
+						end = ']'
+					}
+					if err := e.WriteToken(end); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if d.context == objKey {
+				if _, err := d.next(); err != nil {
+					return err
+				}
+				node = state.reset(d.path)
+			} else {
+				// match the path of the next element, which the Decoder has not yet counted
+				d.path.incTop()
+				node = state.reset(d.path)
+				d.path.top().index--
+			}
+
+		default:
+			if !d.More() {
+				// the end of the stream, or an error
+				_, err := d.next()
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+			node = state.reset(d.path)
+		}
+
+		switch {
+		case node == nil:
+			// nothing within the value is selected
+			if _, err := d.skip(false); err != nil {
+				return err
+			}
+
+		case p.isSelected(node):
+			// the value is selected, so open the enclosing objects and arrays and copy it
+			raw, err := d.skip(true)
+			if err != nil {
+				return err
+			}
+			raw = append([]byte(nil), raw...)
+			if err = p.open(e, levels); err != nil {
+				return err
+			}
+			if err = p.position(e, d.path, len(levels)); err != nil {
+				return err
+			}
+			if err = e.writeRaw(raw); err != nil {
+				return err
+			}
+
+		default:
+			// paths within the value may be selected, so descend into it without writing anything yet
+			kind, err := d.next()
+			if err != nil {
+				return err
+			}
+			if kind != tokenObjectStart && kind != tokenArrayStart {
+				continue
+			}
+			level := projectLevel{delim: '{'}
+			if kind == tokenArrayStart {
+				level.delim = '['
+			}
+			if len(d.path) > 1 {
+				parent := d.path[len(d.path)-2]
+				level.key, level.index = string(parent.key), parent.index
+			}
+			levels = append(levels, level)
+		}
+	}
+}
+
+// open writes the opening delimiters of the levels that have not yet been written, preceded by their keys or by
+// the nulls that preserve their indices.
+func (p *Projection) open(e *Encoder, levels []projectLevel) error {
+	for i := range levels {
+		l := &levels[i]
+		if l.written {
+			continue
+		}
+		if i > 0 {
+			var err error
+			if levels[i-1].delim == '{' {
+				err = e.WriteKey(l.key)
+			} else {
+				err = p.fill(e, l.index)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if err := e.WriteToken(l.delim); err != nil {
+			return err
+		}
+		l.written = true
+	}
+	return nil
+}
+
+// position writes the key or the nulls that precede the value at the given depth of path.
+func (p *Projection) position(e *Encoder, path pathStack, depth int) error {
+	if depth == 0 {
+		return nil
+	}
+	f := &path[depth-1]
+	if !f.array {
+		return e.WriteKey(string(f.key))
+	}
+	// the Decoder has counted the value, which has been read
+	return p.fill(e, f.index)
+}
+
+// fill writes nulls in place of the dropped elements that precede index in the current array, if indices are to be
+// preserved.
+func (p *Projection) fill(e *Encoder, index int) error {
+	if !p.PreserveIndices {
+		return nil
+	}
+	for e.path.top().index+1 < index {
+		if err := e.WriteValue(nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package jsonpath
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func project(t *testing.T, p *Projection, in string) string {
+	var out bytes.Buffer
+	require.NoError(t, p.Project(NewDecoder(strings.NewReader(in)), NewEncoder(&out)))
+	return out.String()
+}
+
+func TestProjection(t *testing.T) {
+
+	in := `{"id":7,"name":{"first":"Ann","last":"Lee"},"tags":["a","b"],` +
+		`"orders":[{"id":1,"total":1.50,"lines":[1,2]},{"id":2},{"total":3}],"meta":{"x":{"y":1}}}`
+
+	p := &Projection{}
+	p.Add("id")
+	p.Add("name", "last")
+	p.Add("orders", AnyIndex, "total")
+	p.Add("meta", "x", "z")
+	p.Add("missing", "a")
+
+	exp := `{"id":7,"name":{"last":"Lee"},"orders":[{"total":1.50},{"total":3}]}` + "\n"
+	assert.Equal(t, exp, project(t, p, in))
+
+	p.PreserveIndices = true
+	exp = `{"id":7,"name":{"last":"Lee"},"orders":[{"total":1.50},null,{"total":3}]}` + "\n"
+	assert.Equal(t, exp, project(t, p, in))
+}
+
+func TestProjectionArrays(t *testing.T) {
+
+	in := `[[0,1,2,3],[4,5],{"a":[6,7,8]}]`
+
+	p := &Projection{}
+	p.Add(0, 2)
+	p.Add(2, "a", 2)
+	assert.Equal(t, `[[2],{"a":[8]}]`+"\n", project(t, p, in))
+
+	p.PreserveIndices = true
+	assert.Equal(t, `[[null,null,2],null,{"a":[null,null,8]}]`+"\n", project(t, p, in))
+}
+
+func TestProjectionSelectsWholeValues(t *testing.T) {
+
+	p := &Projection{}
+	p.Add("a")
+	p.Add("a", "b")
+	assert.Equal(t, `{"a":{"b": 1, "c" : [2]}}`+"\n", project(t, p, `{"a":{"b": 1, "c" : [2]},"d":3}`))
+}
+
+func TestProjectionStream(t *testing.T) {
+
+	p := &Projection{}
+	p.Add("a")
+	assert.Equal(t, "{\"a\":1}\n{}\n[]\n", project(t, p, `{"a":1,"b":2} {"b":3} [1] "s"`))
+
+	p.Add()
+	assert.Equal(t, "{\"a\":1,\"b\":2}\n\"s\"\n", project(t, p, `{"a":1,"b":2} "s"`))
+}
@@ -0,0 +1,133 @@
+package jsonpath
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+)
+
+// RedactMode specifies how a Redactor replaces a value.
+type RedactMode int
+
+const (
+	// RedactMask replaces the value with the Redactor's Mask string.
+	RedactMask RedactMode = iota
+	// RedactHash replaces the value with the hex encoded SHA-256 hash of the value, or its HMAC-SHA256 if the
+	// Redactor has a HashKey. Equal values have equal hashes, so redacted values can still be correlated. A string
+	// is hashed without its quotes and escapes; any other value is hashed in its compact JSON form.
+	RedactHash
+	// RedactPlaceholder replaces the value with a placeholder of the same JSON type: "", 0, false, null, {} or [].
+	RedactPlaceholder
+)
+
+// DefaultMask is the Mask used by a Redactor that does not specify one.
+const DefaultMask = "[REDACTED]"
+
+// Redactor copies a JSON stream from a Decoder to an Encoder, replacing the values at certain paths. It is intended
+// for removing personal information such as email addresses, tokens and card numbers from streamed documents.
+// Paths are registered in the same way as PathActions, and AnyIndex may be used in a path to match any array index.
+//
+// A Redactor can be used many times, including concurrently, but Add must not be called concurrently with Redact.
+type Redactor struct {
+	// Mask is the replacement for values redacted with RedactMask. If it is empty DefaultMask is used.
+	Mask string
+	// HashKey is the key for values redacted with RedactHash. If it is set, values are hashed with HMAC-SHA256 so
+	// that short values cannot be recovered by hashing guesses.
+	HashKey []byte
+
+	rules []redactRule
+}
+
+type redactRule struct {
+	mode RedactMode
+	path JsonPath
+}
+
+// RedactionCount reports the number of values redacted at a path registered with Redactor.Add.
+type RedactionCount struct {
+	Path  JsonPath
+	Count int
+}
+
+// Add specifies that the values at path are to be redacted using mode. A later call to Add with the same path
+// replaces the mode of the earlier one, and the path keeps its place in the counts returned by Redact.
+func (r *Redactor) Add(mode RedactMode, path ...interface{}) {
+	for i := range r.rules {
+		if r.rules[i].path.Equal(path) {
+			r.rules[i].mode = mode
+			return
+		}
+	}
+	r.rules = append(r.rules, redactRule{mode: mode, path: path})
+}
+
+// Redact copies the remainder of the JSON stream from d to e, redacting the values at the registered paths. It
+// returns the number of values redacted at each path, in the order that the paths were added.
+//
+// Paths are matched from the root of the stream as with Transformer.Transform, and values that are not redacted are
+// copied as they appear in the input.
+func (r *Redactor) Redact(d *Decoder, e *Encoder) ([]RedactionCount, error) {
+
+	counts := make([]RedactionCount, len(r.rules))
+	t := &Transformer{}
+	for i, rule := range r.rules {
+		i, mode := i, rule.mode
+		counts[i].Path = rule.path
+		t.Map(func(path JsonPath, value json.RawMessage) (interface{}, error) {
+			counts[i].Count++
+			return r.redact(mode, value)
+		}, rule.path...)
+	}
+
+	err := t.Transform(d, e)
+	return counts, err
+}
+
+func (r *Redactor) redact(mode RedactMode, value json.RawMessage) (interface{}, error) {
+	switch mode {
+	case RedactHash:
+		var h hash.Hash
+		if len(r.HashKey) > 0 {
+			h = hmac.New(sha256.New, r.HashKey)
+		} else {
+			h = sha256.New()
+		}
+		if value[0] == '"' {
+			var s string
+			if err := json.Unmarshal(value, &s); err != nil {
+				return nil, err
+			}
+			h.Write([]byte(s))
+		} else {
+			var b bytes.Buffer
+			if err := json.Compact(&b, value); err != nil {
+				return nil, err
+			}
+			h.Write(b.Bytes())
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+
+	case RedactPlaceholder:
+		switch value[0] {
+		case '"':
+			return "", nil
+		case '{':
+			return struct{}{}, nil
+		case '[':
+			return []struct{}{}, nil
+		case 't', 'f':
+			return false, nil
+		case 'n':
+			return nil, nil
+		}
+		return 0, nil
+	}
+
+	if r.Mask == "" {
+		return DefaultMask, nil
+	}
+	return r.Mask, nil
+}
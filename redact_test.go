@@ -0,0 +1,84 @@
+package jsonpath
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func redact(t *testing.T, r *Redactor, in string) (string, []RedactionCount) {
+	var out bytes.Buffer
+	counts, err := r.Redact(NewDecoder(strings.NewReader(in)), NewEncoder(&out))
+	require.NoError(t, err)
+	return out.String(), counts
+}
+
+func TestRedactorModes(t *testing.T) {
+
+	r := &Redactor{}
+	r.Add(RedactMask, "users", AnyIndex, "email")
+	r.Add(RedactPlaceholder, "users", AnyIndex, "profile", AnyIndex)
+	r.Add(RedactHash, "users", AnyIndex, "card")
+	r.Add(RedactMask, "missing")
+
+	in := `{"users":[` +
+		`{"email":"a@example.com","card":"4111 1111","profile":["x",1.5,true,null,{"a":1},[2]]},` +
+		`{"email":"b@example.com","card":{"n": 1}}]}`
+	out, counts := redact(t, r, in)
+
+	cardHash := sha256.Sum256([]byte("4111 1111"))
+	objHash := sha256.Sum256([]byte(`{"n":1}`))
+	exp := `{"users":[` +
+		`{"email":"[REDACTED]","card":"` + hex.EncodeToString(cardHash[:]) + `","profile":["",0,false,null,{},[]]},` +
+		`{"email":"[REDACTED]","card":"` + hex.EncodeToString(objHash[:]) + `"}]}` + "\n"
+	assert.Equal(t, exp, out)
+
+	assert.Equal(t, []RedactionCount{
+		{Path: JsonPath{"users", AnyIndex, "email"}, Count: 2},
+		{Path: JsonPath{"users", AnyIndex, "profile", AnyIndex}, Count: 6},
+		{Path: JsonPath{"users", AnyIndex, "card"}, Count: 2},
+		{Path: JsonPath{"missing"}, Count: 0},
+	}, counts)
+}
+
+func TestRedactorMaskAndHashKey(t *testing.T) {
+
+	r := &Redactor{Mask: "xxx", HashKey: []byte("secret")}
+	r.Add(RedactMask, "token")
+	r.Add(RedactHash, "id")
+
+	out, _ := redact(t, r, `{"token":"abc","id":"abc","n":1}`)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("abc"))
+	assert.Equal(t, `{"token":"xxx","id":"`+hex.EncodeToString(mac.Sum(nil))+`","n":1}`+"\n", out)
+}
+
+func TestRedactorCountsPerCall(t *testing.T) {
+
+	r := &Redactor{}
+	r.Add(RedactMask, AnyIndex)
+
+	_, counts := redact(t, r, `[1,2,3]`)
+	assert.Equal(t, 3, counts[0].Count)
+	_, counts = redact(t, r, `[1]`)
+	assert.Equal(t, 1, counts[0].Count)
+}
+
+func TestRedactorAddReplaces(t *testing.T) {
+
+	r := &Redactor{}
+	r.Add(RedactMask, "a")
+	r.Add(RedactMask, "b")
+	r.Add(RedactPlaceholder, "a")
+
+	out, counts := redact(t, r, `{"a":"x","b":"y"}`)
+	assert.Equal(t, `{"a":"","b":"[REDACTED]"}`+"\n", out)
+	assert.Equal(t, []RedactionCount{{Path: JsonPath{"a"}, Count: 1}, {Path: JsonPath{"b"}, Count: 1}}, counts)
+}
@@ -0,0 +1,211 @@
+package jsonpath
+
+import (
+	"bufio"
+	"io"
+)
+
+// NewRelaxedDecoder creates a Decoder that accepts a relaxed form of JSON commonly used in configuration files.
+// In addition to standard JSON the input may contain
+//
+//   - line comments beginning with // and block comments enclosed in /* */
+//   - trailing commas after the last element of an object or array
+//   - strings enclosed in single quotes
+//   - object keys that are not quoted
+//
+// The Decoder produces the same tokens and paths as it would for the equivalent standard JSON. Offsets reported
+// in syntax errors refer to the equivalent standard JSON rather than to the relaxed input.
+func NewRelaxedDecoder(r io.Reader) *Decoder {
+	input := &countingReader{r: r}
+	relaxed := &relaxedReader{r: bufio.NewReader(input)}
+	return &Decoder{tok: newTokenizer(relaxed), input: input}
+}
+
+// relaxedReader rewrites relaxed JSON read from r into standard JSON.
+type relaxedReader struct {
+	r     *bufio.Reader
+	out   []byte // output that has not yet been read
+	stack []byte // the open delimiters
+	comma bool   // a comma has been read but not yet written
+	space []byte // whitespace following a pending comma
+	key   bool   // the next value is an object key
+	err   error
+}
+
+func (rr *relaxedReader) Read(p []byte) (int, error) {
+	for len(rr.out) == 0 && rr.err == nil {
+		rr.err = rr.step()
+	}
+	n := copy(p, rr.out)
+	rr.out = rr.out[n:]
+	if len(rr.out) == 0 {
+		return n, rr.err
+	}
+	return n, nil
+}
+
+// step reads the next syntactic element of the input and writes its standard form to the output.
+func (rr *relaxedReader) step() error {
+	c, err := rr.r.ReadByte()
+	if err != nil {
+		rr.flushComma()
+		return err
+	}
+
+	switch c {
+	case ' ', '\t', '\n', '\r':
+		rr.whitespace(c)
+	case '/':
+		return rr.comment()
+	case ',':
+		rr.flushComma()
+		rr.comma = true
+		rr.key = len(rr.stack) > 0 && rr.stack[len(rr.stack)-1] == '{'
+	case '}', ']':
+		// a pending comma is trailing and is dropped
+		rr.out = append(rr.out, rr.space...)
+		rr.space = rr.space[:0]
+		rr.comma = false
+		rr.key = false
+		if len(rr.stack) > 0 {
+			rr.stack = rr.stack[:len(rr.stack)-1]
+		}
+		rr.out = append(rr.out, c)
+	case '{', '[':
+		rr.flushComma()
+		rr.key = c == '{'
+		rr.stack = append(rr.stack, c)
+		rr.out = append(rr.out, c)
+	case '"', '\'':
+		rr.flushComma()
+		rr.key = false
+		return rr.quoted(c)
+	default:
+		rr.flushComma()
+		if rr.key && isIdentByte(c) {
+			rr.key = false
+			return rr.ident(c)
+		}
+		rr.key = false
+		rr.out = append(rr.out, c)
+	}
+	return nil
+}
+
+// whitespace writes the whitespace character c, holding it back if it follows a pending comma.
+func (rr *relaxedReader) whitespace(c byte) {
+	if rr.comma {
+		rr.space = append(rr.space, c)
+	} else {
+		rr.out = append(rr.out, c)
+	}
+}
+
+func (rr *relaxedReader) flushComma() {
+	if rr.comma {
+		rr.out = append(append(rr.out, ','), rr.space...)
+		rr.space = rr.space[:0]
+		rr.comma = false
+	}
+}
+
+// comment skips a comment following a '/', replacing it with whitespace.
+func (rr *relaxedReader) comment() error {
+	c, err := rr.r.ReadByte()
+	if err != nil {
+		rr.out = append(rr.out, '/')
+		return err
+	}
+	switch c {
+	case '/':
+		for {
+			c, err = rr.r.ReadByte()
+			if err != nil {
+				return err
+			}
+			if c == '\n' {
+				rr.whitespace('\n')
+				return nil
+			}
+		}
+	case '*':
+		var prev byte
+		for {
+			c, err = rr.r.ReadByte()
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			} else if err != nil {
+				return err
+			}
+			if prev == '*' && c == '/' {
+				rr.whitespace(' ')
+				return nil
+			}
+			prev = c
+		}
+	default:
+		// not a comment; let the JSON decoder report the error
+		rr.out = append(rr.out, '/', c)
+		return nil
+	}
+}
+
+// quoted copies a string enclosed in the quote character q, converting it to a double quoted string.
+func (rr *relaxedReader) quoted(q byte) error {
+	rr.out = append(rr.out, '"')
+	for {
+		c, err := rr.r.ReadByte()
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		} else if err != nil {
+			return err
+		}
+		switch {
+		case c == q:
+			rr.out = append(rr.out, '"')
+			return nil
+		case c == '\\':
+			e, err := rr.r.ReadByte()
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			} else if err != nil {
+				return err
+			}
+			if e == '\'' {
+				rr.out = append(rr.out, e)
+			} else {
+				rr.out = append(rr.out, c, e)
+			}
+		case c == '"':
+			rr.out = append(rr.out, '\\', c)
+		default:
+			rr.out = append(rr.out, c)
+		}
+	}
+}
+
+// ident copies an unquoted object key beginning with c as a quoted string.
+func (rr *relaxedReader) ident(c byte) error {
+	rr.out = append(rr.out, '"', c)
+	for {
+		c, err := rr.r.ReadByte()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if !isIdentByte(c) {
+			rr.r.UnreadByte()
+			break
+		}
+		rr.out = append(rr.out, c)
+	}
+	rr.out = append(rr.out, '"')
+	return nil
+}
+
+// isIdentByte reports whether c may appear in an unquoted object key. Bytes of multi-byte UTF-8 sequences are
+// accepted.
+func isIdentByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$' || c >= 0x80
+}
@@ -0,0 +1,97 @@
+package jsonpath
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelaxedReader(t *testing.T) {
+
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: `{"a":1}`, out: `{"a":1}`},
+		{in: `{"a":1,}`, out: `{"a":1}`},
+		{in: `[1, 2 , ]`, out: `[1, 2  ]`},
+		{in: `[[1,],[2,],]`, out: `[[1],[2]]`},
+		{in: `{a: 1, $b_2: 'x'}`, out: `{"a": 1, "$b_2": "x"}`},
+		{in: `{true: false, null: null}`, out: `{"true": false, "null": null}`},
+		{in: `['it\'s', 'say "hi"', "\"q\"", 'a\\b']`, out: `["it's", "say \"hi\"", "\"q\"", "a\\b"]`},
+		{in: "{\n// comment\n\"a\": 1 // trailing\n}", out: "{\n\n\"a\": 1 \n}"},
+		{in: `[1, /* two */ 2 /* , */]`, out: `[1,   2  ]`},
+		{in: `{"url": "http://x/*y*/"}`, out: `{"url": "http://x/*y*/"}`},
+		{in: `{'a': {b: [{c: 1,},],},}`, out: `{"a": {"b": [{"c": 1}]}}`},
+	}
+
+	for i, tst := range tests {
+		out, err := ioutil.ReadAll(&relaxedReader{r: bufio.NewReader(strings.NewReader(tst.in))})
+		require.NoError(t, err, "#%v", i)
+		assert.Equal(t, tst.out, string(out), "#%v %s", i, tst.in)
+	}
+}
+
+func TestRelaxedReaderErrors(t *testing.T) {
+
+	for _, in := range []string{`[1 /* open`, `{'a`, `["a`} {
+		_, err := ioutil.ReadAll(&relaxedReader{r: bufio.NewReader(strings.NewReader(in))})
+		assert.Equal(t, io.ErrUnexpectedEOF, err, in)
+	}
+}
+
+func TestRelaxedDecoderTokensAndPaths(t *testing.T) {
+
+	relaxed := `
+	// service configuration
+	{
+		name: 'api',        /* unquoted key */
+		ports: [80, 443,],
+		'tls': {
+			enabled: true,
+			ciphers: ["a", 'b',],
+		},
+	}`
+
+	strict := `{"name": "api", "ports": [80, 443], "tls": {"enabled": true, "ciphers": ["a", "b"]}}`
+
+	rd := NewRelaxedDecoder(strings.NewReader(relaxed))
+	sd := NewDecoder(strings.NewReader(strict))
+	for {
+		rt, rerr := rd.Token()
+		st, serr := sd.Token()
+		require.Equal(t, serr, rerr)
+		if serr == io.EOF {
+			break
+		}
+		assert.Equal(t, st, rt)
+		assert.Equal(t, sd.Path(), rd.Path())
+	}
+}
+
+func TestRelaxedDecoderDecode(t *testing.T) {
+
+	d := NewRelaxedDecoder(bytes.NewBufferString(`{a: {b: [1, 2,], c: 'd', /* done */}, }`))
+	ok, err := d.SeekTo("a")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	var v map[string]interface{}
+	require.NoError(t, d.Decode(&v))
+	assert.Equal(t, map[string]interface{}{"b": []interface{}{float64(1), float64(2)}, "c": "d"}, v)
+}
+
+func TestRelaxedDecoderSyntaxError(t *testing.T) {
+
+	d := NewRelaxedDecoder(strings.NewReader(`{a: [1,,2]}`))
+	err := readAllTokens(d)
+	_, ok := err.(*json.SyntaxError)
+	assert.True(t, ok, "expected *json.SyntaxError, was %v", err)
+}
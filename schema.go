@@ -0,0 +1,1045 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a compiled JSON Schema that validates JSON values as they are read from a Decoder, without holding them
+// in memory. It supports the following keywords of the draft 2020-12 core and validation vocabularies:
+//
+//   - core: $ref, $defs, $anchor, allOf, anyOf, oneOf, not, if, then, else
+//   - objects: properties, patternProperties, additionalProperties, propertyNames, required, dependentRequired,
+//     minProperties, maxProperties
+//   - arrays: prefixItems, items, contains, minContains, maxContains, minItems, maxItems, uniqueItems
+//   - strings: minLength, maxLength, pattern
+//   - numbers: minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf
+//   - any value: type, enum, const
+//
+// References are resolved within the schema document, as JSON pointers such as #/$defs/item or as anchors such as
+// #item; $id is not used as a base for references. Patterns use the syntax of the regexp package. Annotations such
+// as title, description and format are ignored. Compiling a schema that uses dependentSchemas,
+// unevaluatedProperties, unevaluatedItems or $dynamicRef fails, since they cannot be checked as values stream past.
+//
+// Most keywords are checked incrementally as tokens are read. Two kinds of keyword require a value to be buffered:
+// an object or array that is checked by enum or const is read into memory before it is validated, and the elements
+// of an array checked by uniqueItems are each read into memory and kept until the end of the array.
+//
+// A Schema can be used many times, including concurrently.
+type Schema struct {
+	boolean *bool // the schema is true or false
+
+	ref                 *Schema
+	allOf, anyOf, oneOf []*Schema
+	not, cond, then     *Schema
+	els                 *Schema
+
+	types    []string
+	enum     []interface{}
+	constant interface{}
+	hasConst bool
+
+	properties           map[string]*Schema
+	patternProperties    []patternSchema
+	additionalProperties *Schema
+	propertyNames        *Schema
+	required             []string
+	dependentRequired    map[string][]string
+	minProperties        int
+	maxProperties        int
+
+	prefixItems []*Schema
+	items       *Schema
+	contains    *Schema
+	minContains int
+	maxContains int
+	minItems    int
+	maxItems    int
+	uniqueItems bool
+
+	minLength int
+	maxLength int
+	pattern   *regexp.Regexp
+
+	minimum, maximum                   *big.Rat
+	exclusiveMinimum, exclusiveMaximum *big.Rat
+	multipleOf                         *big.Rat
+}
+
+// patternSchema is an entry of patternProperties.
+type patternSchema struct {
+	re     *regexp.Regexp
+	schema *Schema
+}
+
+// ValidationError describes a value that does not conform to a Schema.
+type ValidationError struct {
+	Path    JsonPath // path of the value, relative to the value being validated
+	Keyword string   // the schema keyword that the value violates
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("jsonpath: %s at %v: %s", e.Keyword, e.Path, e.Message)
+}
+
+// CompileSchema compiles a JSON Schema.
+func CompileSchema(schema []byte) (*Schema, error) {
+	dec := json.NewDecoder(bytes.NewReader(schema))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	c := &schemaCompiler{doc: doc, schemas: map[string]*Schema{}, anchors: map[string]*Schema{}}
+	s, err := c.compile(doc, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range c.refs {
+		if err := c.resolve(r); err != nil {
+			return nil, err
+		}
+	}
+	if s.cyclic(map[*Schema]int{}) {
+		return nil, fmt.Errorf("jsonpath: invalid schema: a reference applies a schema to the value it is applied to")
+	}
+	return s, nil
+}
+
+// cyclic reports whether a schema reachable from s applies itself to the same value through $ref or the
+// combinators, which would make validation loop forever. state records the schemas visited: 1 while their
+// subschemas are being visited, and 2 afterwards.
+func (s *Schema) cyclic(state map[*Schema]int) bool {
+	switch state[s] {
+	case 1:
+		return true
+	case 2:
+		return false
+	}
+	state[s] = 1
+	same := append(append(append([]*Schema{s.ref, s.not, s.cond, s.then, s.els}, s.allOf...), s.anyOf...), s.oneOf...)
+	for _, c := range same {
+		if c != nil && c.cyclic(state) {
+			return true
+		}
+	}
+	state[s] = 2
+
+	// schemas that apply to members and elements begin new chains
+	var within []*Schema
+	for _, c := range s.properties {
+		within = append(within, c)
+	}
+	for _, p := range s.patternProperties {
+		within = append(within, p.schema)
+	}
+	within = append(append(within, s.additionalProperties, s.propertyNames, s.items, s.contains), s.prefixItems...)
+	for _, c := range within {
+		if c != nil && state[c] == 0 && c.cyclic(state) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaCompiler holds the state of CompileSchema.
+type schemaCompiler struct {
+	doc     interface{}
+	schemas map[string]*Schema // compiled schemas by the JSON pointer of their location
+	anchors map[string]*Schema
+	refs    []schemaRef // references to resolve once the whole document is compiled
+}
+
+type schemaRef struct {
+	from *Schema
+	ref  string
+	ptr  string
+}
+
+// compile compiles the schema v at the location ptr.
+func (c *schemaCompiler) compile(v interface{}, ptr string) (*Schema, error) {
+
+	if s, ok := c.schemas[ptr]; ok {
+		return s, nil
+	}
+	s := &Schema{maxProperties: -1, minContains: 1, maxContains: -1, maxItems: -1, maxLength: -1}
+	c.schemas[ptr] = s
+
+	if b, ok := v.(bool); ok {
+		s.boolean = &b
+		return s, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, c.invalid(ptr, "a schema must be an object or a boolean")
+	}
+
+	// compile the keywords in a fixed order so that errors are deterministic
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := c.keyword(s, k, m[k], ptr+"/"+escapePointer(k)); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// keyword compiles the keyword k with the value v, located at ptr, into s.
+func (c *schemaCompiler) keyword(s *Schema, k string, v interface{}, ptr string) error {
+	var err error
+	switch k {
+	case "$ref":
+		ref, ok := v.(string)
+		if !ok {
+			return c.invalid(ptr, "must be a string")
+		}
+		c.refs = append(c.refs, schemaRef{from: s, ref: ref, ptr: ptr})
+	case "$anchor":
+		name, ok := v.(string)
+		if !ok {
+			return c.invalid(ptr, "must be a string")
+		}
+		c.anchors[name] = s
+	case "$defs":
+		_, err = c.schemaMap(v, ptr)
+	case "allOf":
+		s.allOf, err = c.schemaList(v, ptr)
+	case "anyOf":
+		s.anyOf, err = c.schemaList(v, ptr)
+	case "oneOf":
+		s.oneOf, err = c.schemaList(v, ptr)
+	case "not":
+		s.not, err = c.compile(v, ptr)
+	case "if":
+		s.cond, err = c.compile(v, ptr)
+	case "then":
+		s.then, err = c.compile(v, ptr)
+	case "else":
+		s.els, err = c.compile(v, ptr)
+
+	case "type":
+		switch t := v.(type) {
+		case string:
+			s.types = []string{t}
+		case []interface{}:
+			s.types, err = c.stringList(v, ptr)
+		default:
+			return c.invalid(ptr, "must be a string or an array")
+		}
+		for _, t := range s.types {
+			switch t {
+			case "null", "boolean", "object", "array", "number", "integer", "string":
+			default:
+				return c.invalid(ptr, fmt.Sprintf("unknown type %q", t))
+			}
+		}
+	case "enum":
+		var ok bool
+		if s.enum, ok = v.([]interface{}); !ok {
+			return c.invalid(ptr, "must be an array")
+		}
+	case "const":
+		s.constant, s.hasConst = v, true
+
+	case "properties":
+		s.properties, err = c.schemaMap(v, ptr)
+	case "patternProperties":
+		var m map[string]*Schema
+		if m, err = c.schemaMap(v, ptr); err != nil {
+			return err
+		}
+		patterns := make([]string, 0, len(m))
+		for p := range m {
+			patterns = append(patterns, p)
+		}
+		sort.Strings(patterns)
+		for _, p := range patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return c.invalid(ptr, err.Error())
+			}
+			s.patternProperties = append(s.patternProperties, patternSchema{re: re, schema: m[p]})
+		}
+	case "additionalProperties":
+		s.additionalProperties, err = c.compile(v, ptr)
+	case "propertyNames":
+		s.propertyNames, err = c.compile(v, ptr)
+	case "required":
+		s.required, err = c.stringList(v, ptr)
+	case "dependentRequired":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return c.invalid(ptr, "must be an object")
+		}
+		s.dependentRequired = map[string][]string{}
+		for k, names := range m {
+			if s.dependentRequired[k], err = c.stringList(names, ptr+"/"+escapePointer(k)); err != nil {
+				return err
+			}
+		}
+	case "minProperties":
+		s.minProperties, err = c.count(v, ptr)
+	case "maxProperties":
+		s.maxProperties, err = c.count(v, ptr)
+
+	case "prefixItems":
+		s.prefixItems, err = c.schemaList(v, ptr)
+	case "items":
+		s.items, err = c.compile(v, ptr)
+	case "contains":
+		s.contains, err = c.compile(v, ptr)
+	case "minContains":
+		s.minContains, err = c.count(v, ptr)
+	case "maxContains":
+		s.maxContains, err = c.count(v, ptr)
+	case "minItems":
+		s.minItems, err = c.count(v, ptr)
+	case "maxItems":
+		s.maxItems, err = c.count(v, ptr)
+	case "uniqueItems":
+		var ok bool
+		if s.uniqueItems, ok = v.(bool); !ok {
+			return c.invalid(ptr, "must be a boolean")
+		}
+
+	case "minLength":
+		s.minLength, err = c.count(v, ptr)
+	case "maxLength":
+		s.maxLength, err = c.count(v, ptr)
+	case "pattern":
+		p, ok := v.(string)
+		if !ok {
+			return c.invalid(ptr, "must be a string")
+		}
+		if s.pattern, err = regexp.Compile(p); err != nil {
+			return c.invalid(ptr, err.Error())
+		}
+
+	case "minimum":
+		s.minimum, err = c.number(v, ptr)
+	case "maximum":
+		s.maximum, err = c.number(v, ptr)
+	case "exclusiveMinimum":
+		s.exclusiveMinimum, err = c.number(v, ptr)
+	case "exclusiveMaximum":
+		s.exclusiveMaximum, err = c.number(v, ptr)
+	case "multipleOf":
+		if s.multipleOf, err = c.number(v, ptr); err == nil && s.multipleOf.Sign() <= 0 {
+			return c.invalid(ptr, "must be greater than 0")
+		}
+
+	case "dependentSchemas", "unevaluatedProperties", "unevaluatedItems", "$dynamicRef", "$recursiveRef":
+		return c.invalid(ptr, "keyword is not supported")
+	}
+	return err
+}
+
+// resolve resolves a $ref.
+func (c *schemaCompiler) resolve(r schemaRef) error {
+	if !strings.HasPrefix(r.ref, "#") {
+		return c.invalid(r.ptr, fmt.Sprintf("cannot resolve %q: only references within the schema are supported", r.ref))
+	}
+	frag := r.ref[1:]
+	if frag != "" && frag[0] != '/' {
+		s, ok := c.anchors[frag]
+		if !ok {
+			return c.invalid(r.ptr, fmt.Sprintf("no anchor %q", frag))
+		}
+		r.from.ref = s
+		return nil
+	}
+
+	tokens, err := parsePointer(frag)
+	if err != nil {
+		return c.invalid(r.ptr, err.Error())
+	}
+	v, ptr := c.doc, ""
+	for _, t := range tokens {
+		switch container := v.(type) {
+		case map[string]interface{}:
+			v = container[t]
+		case []interface{}:
+			i, err := strconv.Atoi(t)
+			if err != nil || i < 0 || i >= len(container) {
+				v = nil
+			} else {
+				v = container[i]
+			}
+		default:
+			v = nil
+		}
+		if v == nil {
+			return c.invalid(r.ptr, fmt.Sprintf("cannot resolve %q", r.ref))
+		}
+		ptr += "/" + escapePointer(t)
+	}
+	r.from.ref, err = c.compile(v, ptr)
+	return err
+}
+
+func (c *schemaCompiler) schemaList(v interface{}, ptr string) ([]*Schema, error) {
+	list, ok := v.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, c.invalid(ptr, "must be a non-empty array")
+	}
+	schemas := make([]*Schema, len(list))
+	for i, e := range list {
+		var err error
+		if schemas[i], err = c.compile(e, ptr+"/"+strconv.Itoa(i)); err != nil {
+			return nil, err
+		}
+	}
+	return schemas, nil
+}
+
+func (c *schemaCompiler) schemaMap(v interface{}, ptr string) (map[string]*Schema, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, c.invalid(ptr, "must be an object")
+	}
+	schemas := make(map[string]*Schema, len(m))
+	for k, e := range m {
+		var err error
+		if schemas[k], err = c.compile(e, ptr+"/"+escapePointer(k)); err != nil {
+			return nil, err
+		}
+	}
+	return schemas, nil
+}
+
+func (c *schemaCompiler) stringList(v interface{}, ptr string) ([]string, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, c.invalid(ptr, "must be an array")
+	}
+	strs := make([]string, len(list))
+	for i, e := range list {
+		if strs[i], ok = e.(string); !ok {
+			return nil, c.invalid(ptr, "must be an array of strings")
+		}
+	}
+	return strs, nil
+}
+
+func (c *schemaCompiler) count(v interface{}, ptr string) (int, error) {
+	if n, ok := v.(json.Number); ok {
+		if r, ok := new(big.Rat).SetString(string(n)); ok && r.IsInt() && r.Sign() >= 0 && r.Num().IsInt64() {
+			return int(r.Num().Int64()), nil
+		}
+	}
+	return 0, c.invalid(ptr, "must be a non-negative integer")
+}
+
+func (c *schemaCompiler) number(v interface{}, ptr string) (*big.Rat, error) {
+	if n, ok := v.(json.Number); ok {
+		if r, ok := new(big.Rat).SetString(string(n)); ok {
+			return r, nil
+		}
+	}
+	return nil, c.invalid(ptr, "must be a number")
+}
+
+func (c *schemaCompiler) invalid(ptr, msg string) error {
+	return fmt.Errorf("jsonpath: invalid schema at %q: %s", "#"+ptr, msg)
+}
+
+// escapePointer escapes a JSON pointer reference token.
+func escapePointer(s string) string {
+	return strings.Replace(strings.Replace(s, "~", "~0", -1), "/", "~1", -1)
+}
+
+// Validate reads the next value from d and validates it against the schema. It returns every violation found, in
+// the order they are detected, or an error if the value cannot be read. Paths are relative to the value, so the
+// value itself has an empty path.
+func (s *Schema) Validate(d *Decoder) ([]*ValidationError, error) {
+	e := &eval{s: s}
+	if err := validateValue(d, []*eval{e}, JsonPath{}); err != nil {
+		return nil, err
+	}
+	return e.errs, nil
+}
+
+// eval is the evaluation of a schema against a value.
+type eval struct {
+	s      *Schema
+	errs   []*ValidationError
+	parent *eval // the evaluation of the enclosing object or array, which receives errs
+
+	// the evaluations of the subschemas that apply to the same value
+	ref                 *eval
+	allOf, anyOf, oneOf []*eval
+	not, cond, then     *eval
+	els                 *eval
+
+	// the state of an object or array
+	count    int             // the number of members or elements
+	seen     map[string]bool // the keys seen, for required and dependentRequired
+	contains int             // the number of elements that match contains
+	elems    []interface{}   // the elements, for uniqueItems
+}
+
+// expand appends e and the evaluations of its subschemas that apply to the same value to all.
+func (e *eval) expand(all []*eval) []*eval {
+	all = append(all, e)
+	sub := func(s *Schema) *eval {
+		c := &eval{s: s}
+		all = c.expand(all)
+		return c
+	}
+	list := func(schemas []*Schema) []*eval {
+		evals := make([]*eval, len(schemas))
+		for i, s := range schemas {
+			evals[i] = sub(s)
+		}
+		return evals
+	}
+
+	s := e.s
+	if s.ref != nil {
+		e.ref = sub(s.ref)
+	}
+	e.allOf, e.anyOf, e.oneOf = list(s.allOf), list(s.anyOf), list(s.oneOf)
+	if s.not != nil {
+		e.not = sub(s.not)
+	}
+	if s.cond != nil {
+		e.cond = sub(s.cond)
+		if s.then != nil {
+			e.then = sub(s.then)
+		}
+		if s.els != nil {
+			e.els = sub(s.els)
+		}
+	}
+	return all
+}
+
+func (e *eval) fail(path JsonPath, keyword, format string, args ...interface{}) {
+	e.errs = append(e.errs, &ValidationError{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+}
+
+// validateValue reads the next value from d and evaluates evals against it.
+func validateValue(d *Decoder, evals []*eval, path JsonPath) error {
+
+	var all []*eval
+	for _, e := range evals {
+		all = e.expand(all)
+	}
+
+	// an object or array compared with enum or const is read into memory
+	var value interface{}
+	for _, e := range all {
+		if e.s.enum != nil || e.s.hasConst {
+			c, err := d.peekValue()
+			if err != nil {
+				return err
+			}
+			if c == '{' || c == '[' {
+				raw, err := d.skip(true)
+				if err != nil {
+					return err
+				}
+				raw = append([]byte(nil), raw...)
+				if value, err = decodeSchemaValue(raw); err != nil {
+					return err
+				}
+				d = NewDecoder(bytes.NewReader(raw))
+			}
+			break
+		}
+	}
+
+	kind, err := d.next()
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case tokenObjectStart:
+		err = validateObject(d, all, path)
+	case tokenArrayStart:
+		err = validateArray(d, all, path)
+	case tokenString:
+		value = d.tok.string()
+	case tokenNumber:
+		value = json.Number(d.tok.raw)
+	case tokenTrue, tokenFalse:
+		value = kind == tokenTrue
+	}
+	if err != nil {
+		return err
+	}
+
+	// finish the evaluations of subschemas before those that depend on them
+	for i := len(all) - 1; i >= 0; i-- {
+		all[i].finish(kind, value, path)
+	}
+	return nil
+}
+
+// validateObject evaluates all against the members of an object, whose start has been read from d.
+func validateObject(d *Decoder, all []*eval, path JsonPath) error {
+
+	for _, e := range all {
+		if len(e.s.required) > 0 || len(e.s.dependentRequired) > 0 {
+			e.seen = map[string]bool{}
+		}
+	}
+	for d.More() {
+		if _, err := d.next(); err != nil {
+			return err
+		}
+		key := string(d.path.top().key)
+		member := append(path[:len(path):len(path)], key)
+
+		var children []*eval
+		for _, e := range all {
+			e.count++
+			if e.seen != nil {
+				e.seen[key] = true
+			}
+			s := e.s
+			if s.propertyNames != nil {
+				if errs, err := validateKey(s.propertyNames, key); err != nil {
+					return err
+				} else if len(errs) > 0 {
+					e.fail(path, "propertyNames", "property name %q is not valid", key)
+				}
+			}
+
+			matched := false
+			if c, ok := s.properties[key]; ok {
+				children = append(children, &eval{s: c, parent: e})
+				matched = true
+			}
+			for _, p := range s.patternProperties {
+				if p.re.MatchString(key) {
+					children = append(children, &eval{s: p.schema, parent: e})
+					matched = true
+				}
+			}
+			if !matched && s.additionalProperties != nil {
+				children = append(children, &eval{s: s.additionalProperties, parent: e})
+			}
+		}
+
+		if err := validateChildren(d, children, member); err != nil {
+			return err
+		}
+	}
+	_, err := d.next()
+	return err
+}
+
+// validateKey validates an object key against the propertyNames schema s.
+func validateKey(s *Schema, key string) ([]*ValidationError, error) {
+	b, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.Validate(NewDecoder(bytes.NewReader(b)))
+}
+
+// validateArray evaluates all against the elements of an array, whose start has been read from d.
+func validateArray(d *Decoder, all []*eval, path JsonPath) error {
+
+	for i := 0; d.More(); i++ {
+		elem := append(path[:len(path):len(path)], i)
+
+		var children []*eval
+		unique := false
+		for _, e := range all {
+			e.count++
+			s := e.s
+			if i < len(s.prefixItems) {
+				children = append(children, &eval{s: s.prefixItems[i], parent: e})
+			} else if s.items != nil {
+				children = append(children, &eval{s: s.items, parent: e})
+			}
+			if s.contains != nil {
+				// the result is counted rather than passed to e
+				children = append(children, &eval{s: s.contains})
+			}
+			unique = unique || s.uniqueItems
+		}
+
+		if unique {
+			// the element is kept for comparison with the others
+			raw, err := d.skip(true)
+			if err != nil {
+				return err
+			}
+			raw = append([]byte(nil), raw...)
+			value, err := decodeSchemaValue(raw)
+			if err != nil {
+				return err
+			}
+			for _, e := range all {
+				if e.s.uniqueItems {
+					e.elems = append(e.elems, value)
+				}
+			}
+			if err := validateChildren(NewDecoder(bytes.NewReader(raw)), children, elem); err != nil {
+				return err
+			}
+		} else if err := validateChildren(d, children, elem); err != nil {
+			return err
+		}
+
+		// count the elements that match contains
+		j := 0
+		for _, e := range all {
+			if e.s.contains == nil {
+				continue
+			}
+			for children[j].parent != nil {
+				j++
+			}
+			if len(children[j].errs) == 0 {
+				e.contains++
+			}
+			j++
+		}
+	}
+	_, err := d.next()
+	return err
+}
+
+// validateChildren reads the next value from d and evaluates children against it, passing their errors to their
+// parents. The value is skipped if there are no children.
+func validateChildren(d *Decoder, children []*eval, path JsonPath) error {
+	if len(children) == 0 {
+		_, err := d.skip(false)
+		return err
+	}
+	if err := validateValue(d, children, path); err != nil {
+		return err
+	}
+	for _, c := range children {
+		if c.parent != nil {
+			c.parent.errs = append(c.parent.errs, c.errs...)
+		}
+	}
+	return nil
+}
+
+// finish completes the evaluation once the whole value has been read. kind is the kind of the value's first token
+// and value is the value of a scalar, or of an object or array that has been read into memory.
+func (e *eval) finish(kind tokenKind, value interface{}, path JsonPath) {
+
+	s := e.s
+	if s.boolean != nil {
+		if !*s.boolean {
+			e.fail(path, "false", "no value is allowed")
+		}
+		return
+	}
+
+	if len(s.types) > 0 && !typeMatches(s.types, kind, value) {
+		e.fail(path, "type", "expected %s but found %s", strings.Join(s.types, " or "), typeName(kind))
+	}
+	if s.enum != nil {
+		found := false
+		for _, v := range s.enum {
+			if valuesEqual(v, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			e.fail(path, "enum", "value is not one of the allowed values")
+		}
+	}
+	if s.hasConst && !valuesEqual(s.constant, value) {
+		e.fail(path, "const", "value is not the required value")
+	}
+
+	switch kind {
+	case tokenObjectStart:
+		e.finishObject(path)
+	case tokenArrayStart:
+		e.finishArray(path)
+	case tokenString:
+		e.finishString(value.(string), path)
+	case tokenNumber:
+		e.finishNumber(value.(json.Number), path)
+	}
+
+	// combine the results of the subschemas
+	if e.ref != nil {
+		e.errs = append(e.errs, e.ref.errs...)
+	}
+	for _, c := range e.allOf {
+		e.errs = append(e.errs, c.errs...)
+	}
+	if len(e.anyOf) > 0 && passing(e.anyOf) == 0 {
+		e.fail(path, "anyOf", "value does not match any of the schemas")
+	}
+	if n := passing(e.oneOf); len(e.oneOf) > 0 && n != 1 {
+		e.fail(path, "oneOf", "value matches %d of the schemas instead of exactly one", n)
+	}
+	if e.not != nil && len(e.not.errs) == 0 {
+		e.fail(path, "not", "value matches a schema that it must not match")
+	}
+	if e.cond != nil {
+		if len(e.cond.errs) == 0 && e.then != nil {
+			e.errs = append(e.errs, e.then.errs...)
+		} else if len(e.cond.errs) > 0 && e.els != nil {
+			e.errs = append(e.errs, e.els.errs...)
+		}
+	}
+}
+
+func (e *eval) finishObject(path JsonPath) {
+	s := e.s
+	if e.count < s.minProperties {
+		e.fail(path, "minProperties", "object has %d properties, fewer than %d", e.count, s.minProperties)
+	}
+	if s.maxProperties >= 0 && e.count > s.maxProperties {
+		e.fail(path, "maxProperties", "object has %d properties, more than %d", e.count, s.maxProperties)
+	}
+	for _, k := range s.required {
+		if !e.seen[k] {
+			e.fail(path, "required", "missing property %q", k)
+		}
+	}
+	keys := make([]string, 0, len(s.dependentRequired))
+	for k := range s.dependentRequired {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !e.seen[k] {
+			continue
+		}
+		for _, r := range s.dependentRequired[k] {
+			if !e.seen[r] {
+				e.fail(path, "dependentRequired", "missing property %q, which is required by %q", r, k)
+			}
+		}
+	}
+}
+
+func (e *eval) finishArray(path JsonPath) {
+	s := e.s
+	if e.count < s.minItems {
+		e.fail(path, "minItems", "array has %d items, fewer than %d", e.count, s.minItems)
+	}
+	if s.maxItems >= 0 && e.count > s.maxItems {
+		e.fail(path, "maxItems", "array has %d items, more than %d", e.count, s.maxItems)
+	}
+	if s.contains != nil {
+		if e.contains < s.minContains {
+			e.fail(path, "contains", "array has %d matching items, fewer than %d", e.contains, s.minContains)
+		}
+		if s.maxContains >= 0 && e.contains > s.maxContains {
+			e.fail(path, "maxContains", "array has %d matching items, more than %d", e.contains, s.maxContains)
+		}
+	}
+	if s.uniqueItems {
+	unique:
+		for i := range e.elems {
+			for j := i + 1; j < len(e.elems); j++ {
+				if valuesEqual(e.elems[i], e.elems[j]) {
+					e.fail(path, "uniqueItems", "items %d and %d are equal", i, j)
+					break unique
+				}
+			}
+		}
+	}
+}
+
+func (e *eval) finishString(v string, path JsonPath) {
+	s := e.s
+	n := utf8.RuneCountInString(v)
+	if n < s.minLength {
+		e.fail(path, "minLength", "string has %d characters, fewer than %d", n, s.minLength)
+	}
+	if s.maxLength >= 0 && n > s.maxLength {
+		e.fail(path, "maxLength", "string has %d characters, more than %d", n, s.maxLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(v) {
+		e.fail(path, "pattern", "string does not match %q", s.pattern.String())
+	}
+}
+
+func (e *eval) finishNumber(v json.Number, path JsonPath) {
+	s := e.s
+	if s.minimum == nil && s.maximum == nil && s.exclusiveMinimum == nil && s.exclusiveMaximum == nil &&
+		s.multipleOf == nil {
+		return
+	}
+	r, ok := new(big.Rat).SetString(string(v))
+	if !ok {
+		return
+	}
+	if s.minimum != nil && r.Cmp(s.minimum) < 0 {
+		e.fail(path, "minimum", "%s is less than %s", v, s.minimum.RatString())
+	}
+	if s.maximum != nil && r.Cmp(s.maximum) > 0 {
+		e.fail(path, "maximum", "%s is greater than %s", v, s.maximum.RatString())
+	}
+	if s.exclusiveMinimum != nil && r.Cmp(s.exclusiveMinimum) <= 0 {
+		e.fail(path, "exclusiveMinimum", "%s is not greater than %s", v, s.exclusiveMinimum.RatString())
+	}
+	if s.exclusiveMaximum != nil && r.Cmp(s.exclusiveMaximum) >= 0 {
+		e.fail(path, "exclusiveMaximum", "%s is not less than %s", v, s.exclusiveMaximum.RatString())
+	}
+	if s.multipleOf != nil && !new(big.Rat).Quo(r, s.multipleOf).IsInt() {
+		e.fail(path, "multipleOf", "%s is not a multiple of %s", v, s.multipleOf.RatString())
+	}
+}
+
+// passing returns the number of evals without errors.
+func passing(evals []*eval) int {
+	n := 0
+	for _, e := range evals {
+		if len(e.errs) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// typeName returns the JSON Schema type of a value that begins with a token of the given kind.
+func typeName(kind tokenKind) string {
+	switch kind {
+	case tokenObjectStart:
+		return "object"
+	case tokenArrayStart:
+		return "array"
+	case tokenString:
+		return "string"
+	case tokenNumber:
+		return "number"
+	case tokenTrue, tokenFalse:
+		return "boolean"
+	}
+	return "null"
+}
+
+// typeMatches reports whether a value matches one of types.
+func typeMatches(types []string, kind tokenKind, value interface{}) bool {
+	name := typeName(kind)
+	for _, t := range types {
+		if t == name {
+			return true
+		}
+		if t == "integer" && kind == tokenNumber {
+			if r, ok := new(big.Rat).SetString(string(value.(json.Number))); ok && r.IsInt() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeSchemaValue decodes a value for comparison with valuesEqual.
+func decodeSchemaValue(raw []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	err := dec.Decode(&v)
+	return v, err
+}
+
+// valuesEqual reports whether two values decoded with UseNumber are equal as JSON values. Numbers are equal if they
+// have the same mathematical value.
+func valuesEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		return numbersEqual(string(a), string(b))
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !valuesEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			w, ok := b[k]
+			if !ok || !valuesEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// numbersEqual reports whether the JSON numbers a and b have the same mathematical value. The numbers are compared
+// in their normalized decimal form rather than expanded, so large exponents cost no more than small ones.
+func numbersEqual(a, b string) bool {
+	negA, digitsA, expA, okA := normalizeNumber(a)
+	negB, digitsB, expB, okB := normalizeNumber(b)
+	return okA && okB && negA == negB && digitsA == digitsB && expA.Cmp(expB) == 0
+}
+
+// normalizeNumber returns the sign, the significant digits and the exponent of the JSON number s, such that its
+// value is 0.digits × 10^exp and digits has neither leading nor trailing zeros. Zero has no digits and is not
+// negative.
+func normalizeNumber(s string) (neg bool, digits string, exp *big.Int, ok bool) {
+	exp = new(big.Int)
+	if strings.HasPrefix(s, "-") {
+		neg, s = true, s[1:]
+	}
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		if _, ok := exp.SetString(s[i+1:], 10); !ok {
+			return false, "", nil, false
+		}
+		s = s[:i]
+	}
+	point := len(s)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		point, s = i, s[:i]+s[i+1:]
+	}
+	if s == "" || strings.Trim(s, "0123456789") != "" {
+		return false, "", nil, false
+	}
+	trimmed := strings.TrimLeft(s, "0")
+	point -= len(s) - len(trimmed)
+	digits = strings.TrimRight(trimmed, "0")
+	if digits == "" {
+		return false, "", exp.SetInt64(0), true
+	}
+	return neg, digits, exp.Add(exp, big.NewInt(int64(point))), true
+}
+
+// peekValue returns the first byte of the next value without consuming it.
+func (d *Decoder) peekValue() (byte, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	if err := d.tok.prepareValue(); err != nil {
+		return 0, d.readError(err)
+	}
+	c, err := d.tok.peek()
+	if err != nil {
+		return 0, d.readError(d.tok.eof(err))
+	}
+	return c, nil
+}
@@ -0,0 +1,201 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// violation is a ValidationError without its message.
+type violation struct {
+	path    string
+	keyword string
+}
+
+func validate(t *testing.T, schema, doc string) []violation {
+	s, err := CompileSchema([]byte(schema))
+	require.NoError(t, err, schema)
+	errs, err := s.Validate(NewDecoder(bytes.NewBufferString(doc)))
+	require.NoError(t, err, doc)
+	var v []violation
+	for _, e := range errs {
+		v = append(v, violation{FormatPath(e.Path), e.Keyword})
+	}
+	return v
+}
+
+func TestSchemaKeywords(t *testing.T) {
+
+	tests := []struct {
+		schema string
+		doc    string
+		errs   []violation
+	}{
+		{`true`, `{"a":1}`, nil},
+		{`false`, `1`, []violation{{"$", "false"}}},
+		{`{"type":"integer"}`, `1.0`, nil},
+		{`{"type":"integer"}`, `1.5`, []violation{{"$", "type"}}},
+		{`{"type":["string","null"]}`, `null`, nil},
+		{`{"type":"object"}`, `[]`, []violation{{"$", "type"}}},
+
+		{`{"minLength":2,"maxLength":3,"pattern":"^a"}`, `"ab"`, nil},
+		{`{"minLength":2}`, `"é"`, []violation{{"$", "minLength"}}},
+		{`{"maxLength":3,"pattern":"^a"}`, `"bcde"`, []violation{{"$", "maxLength"}, {"$", "pattern"}}},
+
+		{`{"minimum":1,"exclusiveMaximum":3}`, `2.999`, nil},
+		{`{"minimum":1}`, `0.5`, []violation{{"$", "minimum"}}},
+		{`{"maximum":1,"exclusiveMinimum":1}`, `1`, []violation{{"$", "exclusiveMinimum"}}},
+		{`{"multipleOf":0.1}`, `0.3`, nil},
+		{`{"multipleOf":0.1}`, `0.35`, []violation{{"$", "multipleOf"}}},
+		{`{"maximum":10}`, `1e2`, []violation{{"$", "maximum"}}},
+
+		{`{"properties":{"a":{"type":"string"}},"required":["a","b"],"additionalProperties":false}`,
+			`{"a":1,"c":2}`, []violation{{"$.a", "type"}, {"$.c", "false"}, {"$", "required"}}},
+		{`{"patternProperties":{"^x-":{"type":"integer"}},"additionalProperties":{"type":"string"}}`,
+			`{"x-a":1,"x-b":"2","c":"d","e":5}`, []violation{{`$["x-b"]`, "type"}, {"$.e", "type"}}},
+		{`{"propertyNames":{"maxLength":2},"minProperties":1,"maxProperties":2}`,
+			`{"ab":1,"abc":2,"d":3}`, []violation{{"$", "propertyNames"}, {"$", "maxProperties"}}},
+		{`{"dependentRequired":{"card":["billing"]}}`, `{"card":1}`, []violation{{"$", "dependentRequired"}}},
+		{`{"dependentRequired":{"card":["billing"]}}`, `{"billing":1}`, nil},
+
+		{`{"prefixItems":[{"type":"string"}],"items":{"type":"integer"},"minItems":2,"maxItems":3}`,
+			`["a",1,"b"]`, []violation{{"$[2]", "type"}}},
+		{`{"items":false,"prefixItems":[true]}`, `[1,2]`, []violation{{"$[1]", "false"}}},
+		{`{"minItems":2}`, `[1]`, []violation{{"$", "minItems"}}},
+		{`{"contains":{"type":"string"}}`, `[1,"a"]`, nil},
+		{`{"contains":{"type":"string"}}`, `[1,2]`, []violation{{"$", "contains"}}},
+		{`{"contains":{"type":"string"},"minContains":2,"maxContains":2}`, `["a","b","c"]`, []violation{{"$", "maxContains"}}},
+		{`{"contains":{"type":"string"},"minContains":0}`, `[]`, nil},
+		{`{"uniqueItems":true}`, `[1,{"a":[1]},"1",{"a":[1.0]}]`, []violation{{"$", "uniqueItems"}}},
+		{`{"uniqueItems":true,"items":{"type":"integer"}}`, `[1,2,"x"]`, []violation{{"$[2]", "type"}}},
+
+		{`{"enum":["a",{"b":[1]}]}`, `{"b":[1.00]}`, nil},
+		{`{"enum":["a",{"b":[1]}]}`, `{"b":[1,2]}`, []violation{{"$", "enum"}}},
+		{`{"const":1e9999999}`, `10E+9999998`, nil},
+		{`{"const":1e9999999}`, `1e9999998`, []violation{{"$", "const"}}},
+		{`{"const":-0.0}`, `0e-99999999999999999999`, nil},
+		{`{"const":120}`, `0.012e4`, nil},
+		{`{"const":120}`, `-120`, []violation{{"$", "const"}}},
+		{`{"uniqueItems":true}`, `[1e1000000,1.5,0.1e1000001]`, []violation{{"$", "uniqueItems"}}},
+		{`{"const":{"a":null},"properties":{"a":{"type":"null"}}}`, `{"a":false}`, []violation{{"$.a", "type"}, {"$", "const"}}},
+		{`{"properties":{"a":{"const":"x"}}}`, `{"a":"x","b":"x"}`, nil},
+	}
+
+	for _, tst := range tests {
+		assert.Equal(t, tst.errs, validate(t, tst.schema, tst.doc), "%s %s", tst.schema, tst.doc)
+	}
+}
+
+func TestSchemaCombinators(t *testing.T) {
+
+	tests := []struct {
+		schema string
+		doc    string
+		errs   []violation
+	}{
+		{`{"allOf":[{"type":"integer"},{"minimum":2}]}`, `1.5`, []violation{{"$", "type"}, {"$", "minimum"}}},
+		{`{"anyOf":[{"type":"string"},{"type":"integer"}]}`, `1`, nil},
+		{`{"anyOf":[{"type":"string"},{"type":"integer"}]}`, `1.5`, []violation{{"$", "anyOf"}}},
+		{`{"oneOf":[{"type":"number"},{"type":"integer"}]}`, `1`, []violation{{"$", "oneOf"}}},
+		{`{"oneOf":[{"type":"number"},{"type":"integer"}]}`, `1.5`, nil},
+		{`{"not":{"type":"null"}}`, `null`, []violation{{"$", "not"}}},
+		{`{"if":{"properties":{"t":{"const":"a"}}},"then":{"required":["a"]},"else":{"required":["b"]}}`,
+			`{"t":"a","b":1}`, []violation{{"$", "required"}}},
+		{`{"if":{"properties":{"t":{"const":"a"}}},"then":{"required":["a"]},"else":{"required":["b"]}}`,
+			`{"t":"c","b":1}`, nil},
+		// errors within a branch are only reported through the combinator
+		{`{"anyOf":[{"properties":{"a":{"type":"string"}}},{"properties":{"a":{"minimum":5}}}]}`,
+			`{"a":1}`, []violation{{"$", "anyOf"}}},
+		{`{"properties":{"a":{"anyOf":[{"items":{"type":"string"}},{"items":{"type":"integer"}}]}}}`,
+			`{"a":[1,2]}`, nil},
+	}
+
+	for _, tst := range tests {
+		assert.Equal(t, tst.errs, validate(t, tst.schema, tst.doc), "%s %s", tst.schema, tst.doc)
+	}
+}
+
+func TestSchemaRefs(t *testing.T) {
+
+	tree := `{
+		"$defs": {
+			"node": {
+				"$anchor": "node",
+				"type": "object",
+				"properties": {
+					"value": {"type": "integer"},
+					"children": {"type": "array", "items": {"$ref": "#/$defs/node"}}
+				},
+				"required": ["value"]
+			}
+		},
+		"$ref": "#node"
+	}`
+	assert.Nil(t, validate(t, tree, `{"value":1,"children":[{"value":2,"children":[]},{"value":3}]}`))
+	assert.Equal(t, []violation{{"$.children[0].children[0].value", "type"}, {"$.children[1]", "required"}},
+		validate(t, tree, `{"value":1,"children":[{"value":2,"children":[{"value":"x"}]},{}]}`))
+
+	list := `{"type":"object","properties":{"next":{"$ref":"#"}}}`
+	assert.Equal(t, []violation{{"$.next.next", "type"}}, validate(t, list, `{"next":{"next":1}}`))
+}
+
+func TestSchemaValidateStream(t *testing.T) {
+
+	s, err := CompileSchema([]byte(`{"type":"object","required":["id"]}`))
+	require.NoError(t, err)
+	d := NewDecoder(bytes.NewBufferString(`{"id":1} {} {"id":2} [`))
+	var counts []int
+	for d.More() {
+		errs, err := s.Validate(d)
+		if err != nil {
+			assert.Error(t, err)
+			break
+		}
+		counts = append(counts, len(errs))
+	}
+	assert.Equal(t, []int{0, 1, 0}, counts)
+
+	errs, err := s.Validate(NewDecoder(bytes.NewBufferString(`{}`)))
+	require.NoError(t, err)
+	require.Len(t, errs, 1)
+	assert.Equal(t, `jsonpath: required at []: missing property "id"`, errs[0].Error())
+}
+
+func TestSchemaValidateSyntaxError(t *testing.T) {
+
+	for _, schema := range []string{`{}`, `{"enum":[1]}`, `{"uniqueItems":true}`} {
+		s, err := CompileSchema([]byte(schema))
+		require.NoError(t, err)
+		_, err = s.Validate(NewDecoder(bytes.NewBufferString(`[1,{"a":}]`)))
+		assert.IsType(t, &json.SyntaxError{}, err, schema)
+	}
+}
+
+func TestCompileSchemaErrors(t *testing.T) {
+
+	for _, schema := range []string{
+		`1`,
+		`{"type":"integr"}`,
+		`{"minLength":-1}`,
+		`{"minLength":1.5}`,
+		`{"multipleOf":0}`,
+		`{"pattern":"("}`,
+		`{"required":"a"}`,
+		`{"allOf":[]}`,
+		`{"properties":{"a":1}}`,
+		`{"$ref":"#/$defs/missing"}`,
+		`{"$ref":"#missing"}`,
+		`{"$ref":"other.json"}`,
+		`{"$ref":"#"}`,
+		`{"anyOf":[{"$ref":"#/$defs/a"}],"$defs":{"a":{"not":{"$ref":"#"}}}}`,
+		`{"unevaluatedProperties":false}`,
+		`{"dependentSchemas":{}}`,
+		`{`,
+	} {
+		_, err := CompileSchema([]byte(schema))
+		assert.Error(t, err, schema)
+	}
+}
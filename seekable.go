@@ -0,0 +1,120 @@
+package jsonpath
+
+import "io"
+
+// SeekableDecoder is a Decoder that reads from an io.ReadSeeker, such as an *os.File, and can return to earlier
+// positions in its input. A position is saved with Checkpoint and returned to with Restore, so a document can be
+// read several times without being reopened, and SeekTo can move backward as well as forward.
+type SeekableDecoder struct {
+	*Decoder
+
+	rs    io.ReadSeeker
+	start int64      // the offset of rs when the decoder was created
+	first Checkpoint // the position before the first token
+}
+
+// Checkpoint is the position of a SeekableDecoder, saved by Checkpoint. It holds the offset of the next unread byte
+// of the input and the state needed to continue reading from it: the path of the most recently read token and the
+// objects and arrays that enclose it. A Checkpoint can only be restored to the decoder that saved it.
+type Checkpoint struct {
+	Offset int64    // the input offset of the next unread byte, as returned by InputOffset
+	Path   JsonPath // the path of the most recently read token
+
+	context jsonContext
+	state   tokenState
+	stack   []tokenState
+	tokens  int
+	keys    []map[string]struct{}
+}
+
+// NewSeekableDecoder returns a SeekableDecoder that reads from rs, starting at its current offset.
+func NewSeekableDecoder(rs io.ReadSeeker) (*SeekableDecoder, error) {
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	d := &SeekableDecoder{Decoder: NewDecoder(rs), rs: rs, start: start}
+	d.first = d.Checkpoint()
+	return d, nil
+}
+
+// Checkpoint returns the current position of the decoder.
+func (d *SeekableDecoder) Checkpoint() Checkpoint {
+	return Checkpoint{
+		Offset:  d.InputOffset(),
+		Path:    d.Path(),
+		context: d.context,
+		state:   d.tok.state,
+		stack:   append([]tokenState(nil), d.tok.stack...),
+		tokens:  d.tok.tokens,
+		keys:    copyKeys(d.keys),
+	}
+}
+
+// copyKeys returns a copy of the keys seen in each enclosing object, which are recorded for duplicate key detection.
+func copyKeys(keys []map[string]struct{}) []map[string]struct{} {
+	if keys == nil {
+		return nil
+	}
+	copied := make([]map[string]struct{}, len(keys))
+	for i, seen := range keys {
+		if seen != nil {
+			copied[i] = make(map[string]struct{}, len(seen))
+			for k := range seen {
+				copied[i][k] = struct{}{}
+			}
+		}
+	}
+	return copied
+}
+
+// Restore returns the decoder to the position saved in c, discarding any buffered input. An error that stopped the
+// decoder is cleared, so Restore can be used to retry from a position before the error.
+func (d *SeekableDecoder) Restore(c Checkpoint) error {
+
+	if _, err := d.rs.Seek(d.start+c.Offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	t := d.tok
+	t.buf = t.buf[:0]
+	t.pos, t.mark, t.pin = 0, 0, -1
+	t.base, t.err = c.Offset, nil
+	t.state = c.state
+	t.stack = append(t.stack[:0], c.stack...)
+	t.raw, t.rawLen, t.escaped = nil, 0, false
+	t.tokens = c.tokens
+
+	d.path.set(c.Path)
+	d.context = c.context
+	d.input.n = c.Offset
+	d.err = nil
+	d.dup = false
+	d.keys = copyKeys(c.keys)
+	return nil
+}
+
+// Rewind returns the decoder to the beginning of its input, to read it again.
+func (d *SeekableDecoder) Rewind() error {
+	return d.Restore(d.first)
+}
+
+// SeekTo moves the decoder to a path as Decoder.SeekTo does, but if the path is not found before the end of the
+// input, it searches again from the beginning of the input, so that it can move backward. If the path is not found
+// at all, the decoder is returned to its position before the call.
+func (d *SeekableDecoder) SeekTo(path ...interface{}) (bool, error) {
+
+	c := d.Checkpoint()
+	found, err := d.Decoder.SeekTo(append([]interface{}(nil), path...)...)
+	if found || err != nil {
+		return found, err
+	}
+	if err := d.Rewind(); err != nil {
+		return false, err
+	}
+	found, err = d.Decoder.SeekTo(append([]interface{}(nil), path...)...)
+	if found || err != nil {
+		return found, err
+	}
+	return false, d.Restore(c)
+}
@@ -0,0 +1,179 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seekableDoc returns a document large enough that a Decoder reads it in several parts.
+func seekableDoc() string {
+	var b strings.Builder
+	b.WriteString(`{"items":[`)
+	for i := 0; i < 500; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"id":%d,"name":"item %d"}`, i, i)
+	}
+	b.WriteString(`],"total":500}`)
+	return b.String()
+}
+
+func TestSeekableDecoderCheckpoint(t *testing.T) {
+
+	doc := seekableDoc()
+	d, err := NewSeekableDecoder(strings.NewReader(doc))
+	require.NoError(t, err)
+
+	ok, err := d.SeekTo("items", 10)
+	require.NoError(t, err)
+	require.True(t, ok)
+	c := d.Checkpoint()
+	assert.Equal(t, JsonPath{"items", 9}, c.Path)
+	assert.True(t, strings.HasPrefix(doc[c.Offset:], `,{"id":10,`))
+
+	var item struct{ ID int }
+	require.NoError(t, d.Decode(&item))
+	assert.Equal(t, 10, item.ID)
+	ok, err = d.SeekTo("total")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// return to element 10, and continue from it to the end of the document
+	require.NoError(t, d.Restore(c))
+	assert.Equal(t, JsonPath{"items", 9}, d.Path())
+	require.NoError(t, d.Decode(&item))
+	assert.Equal(t, 10, item.ID)
+	n := 11
+	for d.More() {
+		require.NoError(t, d.Decode(&item))
+		assert.Equal(t, n, item.ID)
+		n++
+	}
+	assert.Equal(t, 500, n)
+	tok, err := d.Token()
+	require.NoError(t, err)
+	assert.Equal(t, json.Delim(']'), tok)
+	tok, err = d.Token()
+	require.NoError(t, err)
+	assert.Equal(t, KeyString("total"), tok)
+
+	// a checkpoint within an object, after a key
+	require.NoError(t, d.Restore(c))
+	ok, err = d.SeekTo("items", 20, "name")
+	require.NoError(t, err)
+	require.True(t, ok)
+	c = d.Checkpoint()
+	var name string
+	require.NoError(t, d.Decode(&name))
+	require.NoError(t, d.Restore(c))
+	var again string
+	require.NoError(t, d.Decode(&again))
+	assert.Equal(t, "item 20", again)
+	assert.Equal(t, name, again)
+}
+
+func TestSeekableDecoderSeekBackward(t *testing.T) {
+
+	doc := seekableDoc()
+	d, err := NewSeekableDecoder(strings.NewReader(doc))
+	require.NoError(t, err)
+
+	ok, err := d.SeekTo("items", 400, "name")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	path := []interface{}{"items", 3, "id"}
+	ok, err = d.SeekTo(path...)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, []interface{}{"items", 3, "id"}, path)
+	var id int
+	require.NoError(t, d.Decode(&id))
+	assert.Equal(t, 3, id)
+
+	// a missing path leaves the decoder where it was
+	ok, err = d.SeekTo("missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, JsonPath{"items", 3, "id"}, d.Path())
+	ok, err = d.SeekTo("items", 4, "id")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestSeekableDecoderPasses(t *testing.T) {
+
+	// the decoder starts at the current offset of the reader
+	r := strings.NewReader(`junk {"a":[1,2,3]} {"a":[4]}`)
+	_, err := r.Seek(5, io.SeekStart)
+	require.NoError(t, err)
+	d, err := NewSeekableDecoder(r)
+	require.NoError(t, err)
+
+	actions := &PathActions{}
+	var sum int
+	actions.Add(func(d *Decoder) error {
+		var n int
+		err := d.Decode(&n)
+		sum += n
+		return err
+	}, "a", AnyIndex)
+
+	for pass := 0; pass < 2; pass++ {
+		for d.More() {
+			_, err := d.Scan(actions)
+			require.NoError(t, err)
+		}
+		_, err = d.Token()
+		assert.Equal(t, io.EOF, err)
+		require.NoError(t, d.Rewind())
+	}
+	assert.Equal(t, 20, sum)
+}
+
+func TestSeekableDecoderRestoreError(t *testing.T) {
+
+	d, err := NewSeekableDecoder(bytes.NewReader([]byte(`[1, 2 3]`)))
+	require.NoError(t, err)
+	_, err = d.Token()
+	require.NoError(t, err)
+	c := d.Checkpoint()
+	for err == nil {
+		_, err = d.Token()
+	}
+	assert.IsType(t, &json.SyntaxError{}, err)
+
+	// the error is cleared, and is reported again when the same input is read
+	require.NoError(t, d.Restore(c))
+	tok, err := d.Token()
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), tok)
+}
+
+func TestSeekableDecoderRestoreKeys(t *testing.T) {
+
+	// the keys seen before a checkpoint are restored, so a repeated key is still detected
+	d, err := NewSeekableDecoder(strings.NewReader(`{"a":1,"b":2,"a":3}`))
+	require.NoError(t, err)
+	d.SetDuplicateKeyMode(DuplicateKeysError)
+	ok, err := d.SeekTo("b")
+	require.NoError(t, err)
+	require.True(t, ok)
+	c := d.Checkpoint()
+
+	for i := 0; i < 2; i++ {
+		_, err = d.Token()
+		require.NoError(t, err)
+		_, err = d.Token()
+		assert.IsType(t, &DuplicateKeyError{}, err)
+		require.NoError(t, d.Restore(c))
+	}
+}
@@ -0,0 +1,847 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// tokenKind identifies the kind of a token read by the tokenizer.
+type tokenKind int
+
+const (
+	tokenNone tokenKind = iota
+	tokenObjectStart
+	tokenObjectEnd
+	tokenArrayStart
+	tokenArrayEnd
+	tokenKey
+	tokenString
+	tokenNumber
+	tokenTrue
+	tokenFalse
+	tokenNull
+)
+
+// tokenState is the position of the tokenizer within the JSON grammar. The states mirror those used by
+// encoding/json.Decoder.Token.
+type tokenState uint8
+
+const (
+	stateTopValue tokenState = iota
+	stateArrayStart
+	stateArrayValue
+	stateArrayComma
+	stateObjectStart
+	stateObjectKey
+	stateObjectColon
+	stateObjectValue
+	stateObjectComma
+)
+
+const minRead = 4096
+
+var float64Type = reflect.TypeOf(float64(0))
+
+var (
+	errDepthLimit  = errors.New("jsonpath: depth limit exceeded")
+	errStringLimit = errors.New("jsonpath: string length limit exceeded")
+	errTokenLimit  = errors.New("jsonpath: token limit exceeded")
+	// errCaptureLimit is returned when a value being captured is longer than maxCapture bytes.
+	errCaptureLimit = errors.New("jsonpath: captured value limit exceeded")
+)
+
+// tokenizer reads JSON tokens from a stream. Tokens are lexed in place in an internal buffer so that they can be
+// examined, or skipped over, without allocating.
+type tokenizer struct {
+	r    io.Reader
+	buf  []byte
+	pos  int   // position of the next unread byte in buf
+	mark int   // start of the token being read; earlier bytes may be discarded
+	pin  int   // start of a value being captured, or -1
+	base int64 // input offset of buf[0]
+	err  error // error returned by r
+
+	state tokenState
+	stack []tokenState
+
+	comma int64 // input offset of the most recent comma
+
+	raw     []byte // the most recent string, key or number token as it appears in the input
+	rawLen  int    // length of the most recent token, which exceeds len(raw) if skipping discarded part of it
+	escaped bool   // the most recent string or key contains escape sequences
+
+	skipping bool       // the tokens being read are not examined, so string contents need not be retained
+	outer    tokenState // the state before the value being skipped
+
+	maxDepth   int
+	maxString  int
+	maxTokens  int
+	maxCapture int // if positive, the most bytes of a captured value held before errCaptureLimit is returned
+	tokens     int
+}
+
+func newTokenizer(r io.Reader) *tokenizer {
+	return &tokenizer{r: r, pin: -1}
+}
+
+// fill reads more input into the buffer, discarding the bytes before the current token or captured value.
+// It returns false if no more input is available.
+func (t *tokenizer) fill() bool {
+	if t.err != nil {
+		return false
+	}
+	keep := t.mark
+	if t.pin >= 0 && t.pin < keep {
+		keep = t.pin
+	}
+	if keep > 0 {
+		n := copy(t.buf, t.buf[keep:])
+		t.buf = t.buf[:n]
+		t.base += int64(keep)
+		t.pos -= keep
+		t.mark -= keep
+		if t.pin >= 0 {
+			t.pin -= keep
+		}
+	}
+	if t.pin >= 0 && t.maxCapture > 0 && len(t.buf)-t.pin > t.maxCapture {
+		// the captured value is not read any further
+		t.err = errCaptureLimit
+		return false
+	}
+	if cap(t.buf)-len(t.buf) < minRead/2 {
+		buf := make([]byte, len(t.buf), 2*cap(t.buf)+minRead)
+		copy(buf, t.buf)
+		t.buf = buf
+	}
+	for {
+		n, err := t.r.Read(t.buf[len(t.buf):cap(t.buf)])
+		t.buf = t.buf[:len(t.buf)+n]
+		if err != nil {
+			t.err = err
+			return n > 0
+		}
+		if n > 0 {
+			return true
+		}
+	}
+}
+
+// offset returns the input offset of the next unread byte.
+func (t *tokenizer) offset() int64 {
+	return t.base + int64(t.pos)
+}
+
+// peek returns the next byte that is not whitespace without consuming it.
+func (t *tokenizer) peek() (byte, error) {
+	for {
+		for ; t.pos < len(t.buf); t.pos++ {
+			switch c := t.buf[t.pos]; c {
+			case ' ', '\t', '\n', '\r':
+			default:
+				t.mark = t.pos
+				return c, nil
+			}
+		}
+		t.mark = t.pos
+		if !t.fill() {
+			return 0, t.err
+		}
+	}
+}
+
+// more reports whether there is another element in the current array or object.
+func (t *tokenizer) more() bool {
+	c, err := t.peek()
+	return err == nil && c != ']' && c != '}'
+}
+
+// next reads the next token. The literal of a string, key or number token is held in t.raw until the following
+// call. If the token exceeds one of the configured limits it is returned together with a limit error.
+func (t *tokenizer) next() (tokenKind, error) {
+	for {
+		c, err := t.peek()
+		if err != nil {
+			return tokenNone, t.eof(err)
+		}
+		switch c {
+		case '{', '[':
+			if !t.valueAllowed() {
+				if t.state == stateObjectStart || t.state == stateObjectKey {
+					return tokenNone, t.nonStringKey(c)
+				}
+				return tokenNone, t.unexpected(c)
+			}
+			t.pos++
+			t.stack = append(t.stack, t.state)
+			if c == '{' {
+				t.state = stateObjectStart
+				return t.token(tokenObjectStart)
+			}
+			t.state = stateArrayStart
+			return t.token(tokenArrayStart)
+		case '}':
+			if t.state != stateObjectStart && t.state != stateObjectComma {
+				return tokenNone, t.unexpected(c)
+			}
+			t.pos++
+			t.popState()
+			return t.token(tokenObjectEnd)
+		case ']':
+			if t.state != stateArrayStart && t.state != stateArrayComma {
+				return tokenNone, t.unexpected(c)
+			}
+			t.pos++
+			t.popState()
+			return t.token(tokenArrayEnd)
+		case ':':
+			if t.state != stateObjectColon {
+				return tokenNone, t.unexpected(c)
+			}
+			t.pos++
+			t.state = stateObjectValue
+		case ',':
+			if t.state == stateArrayComma {
+				t.state = stateArrayValue
+			} else if t.state == stateObjectComma {
+				t.state = stateObjectKey
+			} else {
+				return tokenNone, t.unexpected(c)
+			}
+			t.comma = t.offset()
+			t.pos++
+		default:
+			if t.state == stateObjectStart || t.state == stateObjectKey {
+				if c != '"' {
+					return tokenNone, t.nonStringKey(c)
+				}
+				if err := t.lexString(); err == errStringLimit {
+					// the key is returned so that the error has its path
+					return tokenKey, err
+				} else if err != nil {
+					return tokenNone, err
+				}
+				t.state = stateObjectColon
+				return t.token(tokenKey)
+			}
+			if !t.valueAllowed() {
+				return tokenNone, t.unexpected(c)
+			}
+			kind, err := t.lexValue(c)
+			if err == errStringLimit {
+				return kind, err
+			} else if err != nil {
+				return tokenNone, err
+			}
+			t.valueEnd()
+			return t.token(kind)
+		}
+	}
+}
+
+// token counts the token and checks it against the configured limits. The tokens of a value being skipped are not
+// counted, since skipValue counts the value as a single token.
+func (t *tokenizer) token(kind tokenKind) (tokenKind, error) {
+	if !t.skipping {
+		if err := t.count(); err != nil {
+			return kind, err
+		}
+	}
+	switch kind {
+	case tokenObjectStart, tokenArrayStart:
+		if t.maxDepth > 0 && len(t.stack) > t.maxDepth {
+			return kind, errDepthLimit
+		}
+	}
+	return kind, nil
+}
+
+// count counts a token and checks the number read against MaxTokens.
+func (t *tokenizer) count() error {
+	t.tokens++
+	if t.maxTokens > 0 && t.tokens > t.maxTokens {
+		return errTokenLimit
+	}
+	return nil
+}
+
+func (t *tokenizer) valueAllowed() bool {
+	switch t.state {
+	case stateTopValue, stateArrayStart, stateArrayValue, stateObjectValue:
+		return true
+	}
+	return false
+}
+
+// valueEnd advances the state after a complete value.
+func (t *tokenizer) valueEnd() {
+	switch t.state {
+	case stateArrayStart, stateArrayValue:
+		t.state = stateArrayComma
+	case stateObjectValue:
+		t.state = stateObjectComma
+	}
+}
+
+func (t *tokenizer) popState() {
+	t.state = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+	t.valueEnd()
+}
+
+// prepareValue consumes the comma or colon that precedes the next value and reports an error if a value may not
+// appear at the current position. The errors are those of encoding/json.Decoder.Decode at the same position.
+func (t *tokenizer) prepareValue() error {
+	c, err := t.peek()
+	if err != nil {
+		return t.eof(err)
+	}
+	switch t.state {
+	case stateArrayComma, stateObjectColon:
+		if c == ']' && t.state == stateArrayComma {
+			return t.invalidChar(t.pos, atValueStart)
+		}
+		if c != ',' && c != ':' || c == ',' && t.state == stateObjectColon || c == ':' && t.state == stateArrayComma {
+			return t.unexpected(c)
+		}
+		t.comma = t.offset()
+		t.pos++
+		if t.state == stateArrayComma {
+			t.state = stateArrayValue
+		} else {
+			t.state = stateObjectValue
+		}
+		if c, err = t.peek(); err != nil {
+			return t.eof(err)
+		}
+	case stateObjectStart, stateObjectKey, stateObjectComma:
+		if c == '}' && t.state != stateObjectKey {
+			return t.invalidChar(t.pos, atValueStart)
+		}
+		if t.state == stateObjectComma {
+			if c != ',' {
+				return t.unexpected(c)
+			}
+			t.comma = t.offset()
+			t.pos++
+			t.state = stateObjectKey
+			if c, err = t.peek(); err != nil {
+				return t.eof(err)
+			}
+		}
+		switch c {
+		case '"':
+			// a key cannot be decoded as a value
+			return syntaxError(notValueStart, t.offset())
+		case '{', '[':
+			// encoding/json reads the whole object or array before reporting that it is not a key
+			off, outer := t.offset(), t.state
+			t.state = stateObjectValue
+			if _, err := t.skip(outer, false); err != nil {
+				return err
+			}
+			return syntaxError(nonStringKey, off)
+		}
+		return t.nonStringKey(c)
+	}
+	switch {
+	case t.state == stateArrayValue && (c == '}' || c == ']'):
+		return t.unexpected(c)
+	case t.state == stateObjectValue && c == ']':
+		return t.unexpected(c)
+	case c == '}' || c == ']':
+		return t.invalidChar(t.pos, atValueStart)
+	}
+	return nil
+}
+
+// nonStringKey returns the error for the character c where an object key is expected. When a token is read,
+// encoding/json reads a value that begins with c before reporting that it is not a string, so an error within the
+// value is reported instead.
+func (t *tokenizer) nonStringKey(c byte) error {
+	if t.skipping || t.state == stateObjectKey && (c == '}' || c == ']') {
+		return t.unexpected(c)
+	}
+	off := t.offset()
+	switch {
+	case c == '{' || c == '[':
+	case c == 't' || c == 'f' || c == 'n' || c == '-' || c >= '0' && c <= '9':
+		if _, err := t.lexValue(c); err != nil {
+			return err
+		}
+	default:
+		return t.unexpected(c)
+	}
+	return syntaxError(nonStringKey, off)
+}
+
+// skipValue consumes the next value without materializing it. If capture is true the value is returned as it
+// appears in the input; the returned slice is only valid until the next call to the tokenizer.
+func (t *tokenizer) skipValue(capture bool) ([]byte, error) {
+	outer := t.state
+	if err := t.prepareValue(); err != nil {
+		return nil, err
+	}
+	return t.skip(outer, capture)
+}
+
+// skip consumes the value at the current position, which was reached from the state outer.
+func (t *tokenizer) skip(outer tokenState, capture bool) ([]byte, error) {
+	if _, err := t.peek(); err != nil {
+		return nil, t.eof(err)
+	}
+	if capture {
+		t.pin = t.pos
+		defer func() { t.pin = -1 }()
+	}
+	depth := len(t.stack)
+	t.skipping, t.outer = true, outer
+	defer func() { t.skipping = false }()
+	for {
+		if _, err := t.next(); err != nil {
+			return nil, err
+		}
+		if len(t.stack) == depth {
+			break
+		}
+	}
+	if err := t.count(); err != nil {
+		return nil, err
+	}
+	if capture {
+		return t.buf[t.pin:t.pos], nil
+	}
+	return nil, nil
+}
+
+// lexValue reads the scalar value beginning with c.
+func (t *tokenizer) lexValue(c byte) (tokenKind, error) {
+	switch c {
+	case '"':
+		return tokenString, t.lexString()
+	case 't':
+		return tokenTrue, t.lexLiteral("true")
+	case 'f':
+		return tokenFalse, t.lexLiteral("false")
+	case 'n':
+		return tokenNull, t.lexLiteral("null")
+	}
+	if c == '-' || c >= '0' && c <= '9' {
+		return tokenNumber, t.lexNumber()
+	}
+	return tokenNone, t.unexpected(c)
+}
+
+// lexString reads the string beginning at the current position. If the string is longer than maxString bytes, as
+// they appear in the input, reading stops as soon as the limit is exceeded, so that the string is not held in
+// memory, and errStringLimit is returned.
+func (t *tokenizer) lexString() error {
+	t.escaped = false
+	t.rawLen = 0
+	i := t.pos + 1
+	for {
+		for i < len(t.buf) {
+			c := t.buf[i]
+			switch {
+			case c == '"':
+				if t.overString(i) {
+					return t.stringLimit()
+				}
+				t.raw = t.buf[t.pos : i+1]
+				t.rawLen += len(t.raw)
+				t.pos = i + 1
+				return nil
+			case c == '\\':
+				n, err := t.escape(i)
+				if err != nil {
+					return err
+				}
+				if n == 0 {
+					goto refill
+				}
+				t.escaped = true
+				i += n
+			case c < 0x20:
+				return t.invalidChar(i, inString)
+			default:
+				i++
+			}
+		}
+	refill:
+		if t.overString(i) {
+			return t.stringLimit()
+		}
+		if t.skipping {
+			// discard the part of the string that has been read
+			t.rawLen += i - t.pos
+			t.pos = i
+			t.mark = i
+		}
+		off := i - t.pos
+		if !t.fill() {
+			if i = t.pos + off; i < len(t.buf) && t.buf[i] == '\\' {
+				// an escape sequence cut short may already be invalid
+				if _, err := t.escape(i); err != nil {
+					return err
+				}
+			}
+			return unexpectedEOF(t.err)
+		}
+		i = t.pos + off
+	}
+}
+
+// overString reports whether the string being read, of which the bytes before buf[i] have been read, exceeds
+// maxString bytes.
+func (t *tokenizer) overString(i int) bool {
+	// rawLen counts the opening quote and any bytes discarded while skipping
+	return t.maxString > 0 && t.rawLen+i-t.pos-1 > t.maxString
+}
+
+// stringLimit returns errStringLimit for the string being read. Unless the string is being skipped, its first
+// maxString+1 bytes are held in t.raw as if they were the whole string, so that the path of a key can be reported.
+func (t *tokenizer) stringLimit() error {
+	if !t.skipping {
+		end := t.pos + 2 + t.maxString
+		t.raw = append(t.buf[t.pos:end:end], '"')
+	}
+	return errStringLimit
+}
+
+// escape validates the escape sequence at buf[i] and returns its length, or zero if more input is required.
+func (t *tokenizer) escape(i int) (int, error) {
+	if i+1 >= len(t.buf) {
+		return 0, nil
+	}
+	switch e := t.buf[i+1]; e {
+	case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+		return 2, nil
+	case 'u':
+		if i+6 > len(t.buf) {
+			if t.err != nil {
+				// at the end of the input, an invalid escape sequence is reported as far as it goes
+				for _, h := range t.buf[i+2:] {
+					if !isHex(h) {
+						return 0, invalidText("escape sequence", string(t.buf[i:]), inString, t.base+int64(i))
+					}
+				}
+			}
+			return 0, nil
+		}
+		for _, h := range t.buf[i+2 : i+6] {
+			if !isHex(h) {
+				return 0, invalidText("escape sequence", string(t.buf[i:i+6]), inString, t.base+int64(i))
+			}
+		}
+		return 6, nil
+	default:
+		return 0, invalidText("escape sequence", string(t.buf[i:i+2]), inString, t.base+int64(i))
+	}
+}
+
+// isHex reports whether c is a hexadecimal digit.
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// number grammar states
+const (
+	numSign    = iota // after '-'
+	numZero           // after a leading '0'
+	numInt            // in the integer part
+	numDot            // after '.'
+	numFrac           // in the fraction
+	numE              // after 'e' or 'E'
+	numExpSign        // after the exponent sign
+	numExp            // in the exponent
+)
+
+// lexNumber reads the number beginning at the current position.
+func (t *tokenizer) lexNumber() error {
+	state := numSign
+	i := t.pos
+	if t.buf[i] == '-' {
+		i++
+	} else if t.buf[i] == '0' {
+		state = numZero
+		i++
+	} else {
+		state = numInt
+		i++
+	}
+	for {
+		for ; i < len(t.buf); i++ {
+			c := t.buf[i]
+			isDigit := c >= '0' && c <= '9'
+			switch state {
+			case numSign:
+				if c == '0' {
+					state = numZero
+				} else if isDigit {
+					state = numInt
+				} else {
+					return t.invalidChar(i, inNumber)
+				}
+				continue
+			case numInt, numZero:
+				if isDigit && state == numInt {
+					continue
+				} else if c == '.' {
+					state = numDot
+					continue
+				} else if c == 'e' || c == 'E' {
+					state = numE
+					continue
+				}
+			case numDot:
+				if !isDigit {
+					return t.invalidChar(i, inNumber)
+				}
+				state = numFrac
+				continue
+			case numFrac:
+				if isDigit {
+					continue
+				} else if c == 'e' || c == 'E' {
+					state = numE
+					continue
+				}
+			case numE:
+				if c == '+' || c == '-' {
+					state = numExpSign
+					continue
+				}
+				fallthrough
+			case numExpSign:
+				if !isDigit {
+					return t.invalidChar(i, inNumber)
+				}
+				state = numExp
+				continue
+			case numExp:
+				if isDigit {
+					continue
+				}
+			}
+			// c ends the number
+			t.raw = t.buf[t.pos:i]
+			t.pos = i
+			return nil
+		}
+		off := i - t.pos
+		if !t.fill() {
+			if t.err != io.EOF {
+				return t.err
+			}
+			switch state {
+			case numZero, numInt, numFrac, numExp:
+				t.raw = t.buf[t.pos:]
+				t.pos = len(t.buf)
+				return nil
+			}
+			return io.ErrUnexpectedEOF
+		}
+		i = t.pos + off
+	}
+}
+
+// lexLiteral reads the literal true, false or null.
+func (t *tokenizer) lexLiteral(lit string) error {
+	for i := 1; i < len(lit); i++ {
+		for t.pos+i >= len(t.buf) {
+			if !t.fill() {
+				return unexpectedEOF(t.err)
+			}
+		}
+		if t.buf[t.pos+i] != lit[i] {
+			return t.invalidChar(t.pos+i, "in literal "+lit+" (expecting "+strconv.QuoteRune(rune(lit[i]))+")")
+		}
+	}
+	t.pos += len(lit)
+	return nil
+}
+
+// string returns the value of the most recent string or key token.
+func (t *tokenizer) string() string {
+	lit := t.raw[1 : len(t.raw)-1]
+	if !t.escaped && utf8.Valid(lit) {
+		return string(lit)
+	}
+	var s string
+	json.Unmarshal(t.raw, &s)
+	return s
+}
+
+// appendString appends the value of the most recent string or key token to dst.
+func (t *tokenizer) appendString(dst []byte) []byte {
+	lit := t.raw[1 : len(t.raw)-1]
+	if !t.escaped && utf8.Valid(lit) {
+		return append(dst, lit...)
+	}
+	return append(dst, t.string()...)
+}
+
+// number returns the value of the most recent number token as a float64, or as a json.Number if useNumber is set.
+func (t *tokenizer) number(useNumber bool) (interface{}, error) {
+	if useNumber {
+		return json.Number(t.raw), nil
+	}
+	f, err := strconv.ParseFloat(string(t.raw), 64)
+	if err != nil {
+		return nil, &json.UnmarshalTypeError{Value: "number " + string(t.raw), Type: float64Type, Offset: t.offset()}
+	}
+	return f, nil
+}
+
+// eof converts an end of input inside a value into io.ErrUnexpectedEOF.
+func (t *tokenizer) eof(err error) error {
+	if err == io.EOF && (len(t.stack) > 0 || t.state != stateTopValue) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// atEnd reports whether all of the input has been read, so that the end of the input does not fall within a token.
+func (t *tokenizer) atEnd() bool {
+	return t.err == io.EOF && t.pos == len(t.buf)
+}
+
+// unexpectedEOF converts an end of input inside a token into io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Phrases used in syntax errors, which are worded as they are by encoding/json.
+const (
+	atValueStart  = "looking for beginning of value"
+	atKeyStart    = "looking for beginning of object key string"
+	afterElement  = "after array element"
+	afterKey      = "after object key"
+	afterKeyValue = "after object key:value pair"
+	inNumber      = "in numeric literal"
+	inString      = "in string"
+	nonStringKey  = "object member name must be a string"
+	missingValue  = "missing value after object key"
+	notValueStart = "not at beginning of value"
+)
+
+// syntaxError returns a *json.SyntaxError with the message msg for the input offset off. encoding/json gives no way
+// to set the message of a json.SyntaxError, so its unexported field is set through reflect.
+func syntaxError(msg string, off int64) error {
+	se := &json.SyntaxError{Offset: off}
+	if f := reflect.ValueOf(se).Elem().FieldByName("msg"); f.IsValid() && f.Kind() == reflect.String {
+		reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem().SetString(msg)
+	}
+	return se
+}
+
+// invalidText returns the error for the invalid text what at the input offset off, described by label, such as
+// "character", and by where.
+func invalidText(label, what, where string, off int64) error {
+	var q string
+	switch {
+	case utf8.RuneCountInString(what) == 1:
+		if r, n := utf8.DecodeRuneInString(what); r == utf8.RuneError && n == 1 {
+			q = `'\x` + strconv.FormatUint(uint64(what[0]), 16) + `'`
+		} else {
+			q = strconv.QuoteRune(r)
+		}
+	case strings.IndexFunc(what, func(r rune) bool {
+		return r == '`' || r == utf8.RuneError || unicode.IsSpace(r) || !unicode.IsPrint(r)
+	}) >= 0:
+		q = strconv.Quote(what)
+	default:
+		q = "`" + what + "`"
+	}
+	return syntaxError("invalid "+label+" "+q+" "+where, off)
+}
+
+// invalidChar returns the error for the invalid character at buf[i].
+func (t *tokenizer) invalidChar(i int, where string) error {
+	off := t.base + int64(i)
+	// read the whole of a multi-byte character so that it can be quoted
+	for len(t.buf)-i < utf8.UTFMax && !utf8.FullRune(t.buf[i:]) {
+		rel := i - t.pos
+		more := t.fill()
+		i = t.pos + rel
+		if !more {
+			break
+		}
+	}
+	_, n := utf8.DecodeRune(t.buf[i:])
+	return invalidText("character", string(t.buf[i:i+n]), where, off)
+}
+
+// unexpected returns the error for the character c at the current position, which may not appear in the current
+// state. Where encoding/json words the error differently when it reads a whole value, with Decode, from when it
+// reads a single token, the error for a value is used while one is being skipped.
+func (t *tokenizer) unexpected(c byte) error {
+	value := t.skipping
+	if value && (t.state == stateArrayStart || t.state == stateArrayValue || t.state == stateObjectValue) {
+		// encoding/json takes a closing delimiter where a value is expected to be the end of the object or array
+		// that encloses the value being read, rather than of the one that it is in
+		switch {
+		case c == ']' && t.outer >= stateObjectKey:
+			return t.invalidChar(t.pos, afterKeyValue)
+		case c == '}' && (t.outer == stateArrayValue || t.outer == stateArrayComma):
+			return t.invalidChar(t.pos, afterElement)
+		}
+	}
+	switch t.state {
+	case stateObjectStart, stateObjectKey:
+		if value {
+			return t.invalidChar(t.pos, atKeyStart)
+		}
+		if t.state == stateObjectKey && (c == '}' || c == ']') {
+			// a token reports the comma that is not followed by a key
+			return invalidText("character", ",", atValueStart, t.comma)
+		}
+	case stateArrayValue:
+		if !value && (c == '}' || c == ']') {
+			return invalidText("character", ",", atValueStart, t.comma)
+		}
+	case stateObjectColon:
+		return t.invalidChar(t.pos, afterKey)
+	case stateObjectValue:
+		if !value && c == '}' {
+			return syntaxError(missingValue, t.offset())
+		}
+		if !value && c == ']' {
+			return t.invalidChar(t.pos, afterKeyValue)
+		}
+	case stateArrayComma:
+		if c == ':' && !value {
+			return t.misplacedColon(afterElement)
+		}
+		return t.invalidChar(t.pos, afterElement)
+	case stateObjectComma:
+		if c == ':' && !value {
+			return t.misplacedColon(afterKeyValue)
+		}
+		return t.invalidChar(t.pos, afterKeyValue)
+	}
+	return t.invalidChar(t.pos, atValueStart)
+}
+
+// misplacedColon returns the error for a colon at the current position that follows a value. When a token is read,
+// encoding/json reports the colon as expecting a comma unless it is followed by the end of an object or array.
+func (t *tokenizer) misplacedColon(where string) error {
+	off := t.offset()
+	t.pos++
+	if c, err := t.peek(); err == nil && (c == '}' || c == ']') {
+		where = atValueStart
+	} else if err != nil && err != io.EOF {
+		return err
+	}
+	return invalidText("character", ":", where, off)
+}
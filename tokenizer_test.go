@@ -0,0 +1,418 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var tokenizerValid = []string{
+	`{}`,
+	`[]`,
+	`  {"a" : 1 , "b":[true,false,null] }  `,
+	`[0, -0, 1.5, -12.25e+3, 6E-2, 1e400000000000000000000000000000000, 123456789012345678901234567890]`,
+	`["", "plain", "esc\"aped", "\\\/\b\f\n\r\t", "é€😀", "café", "日本語"]`,
+	`{"nested":{"a":[{"b":[[],[{}]]}]}}`,
+	`"top"`,
+	`1 2 3`,
+	`{"a":1}{"b":2}[3]`,
+	"[1,\n\t2\r\n]",
+}
+
+var tokenizerInvalid = []string{
+	`[1,]`,
+	`[,1]`,
+	`{"a" 1}`,
+	`{"a":1,}`,
+	`{1:2}`,
+	`[1 2]`,
+	`{"a":1 "b":2}`,
+	`[}`,
+	`{]`,
+	`]`,
+	`[-]`,
+	`[01]`,
+	`[1.]`,
+	`[1.e5]`,
+	`[1e]`,
+	`[+1]`,
+	`[tru]`,
+	`[trux]`,
+	`[nul]`,
+	`["a\x"]`,
+	`["\u12g4"]`,
+	"[\"a\tb\"]",
+	`["abc`,
+	`"abc`,
+	`tru`,
+	`nul`,
+}
+
+// referenceTokens reads all tokens with encoding/json.Decoder.
+func referenceTokens(in string) ([]json.Token, error) {
+	dec := json.NewDecoder(strings.NewReader(in))
+	var out []json.Token
+	for {
+		t, err := dec.Token()
+		if err == io.EOF {
+			return out, nil
+		} else if err != nil {
+			return out, err
+		}
+		out = append(out, t)
+	}
+}
+
+// decoderTokens reads all tokens with the Decoder, converting keys to strings.
+func decoderTokens(r io.Reader) ([]json.Token, error) {
+	dec := NewDecoder(r)
+	var out []json.Token
+	for {
+		t, err := dec.Token()
+		if err == io.EOF {
+			return out, nil
+		} else if err != nil {
+			return out, err
+		}
+		if k, ok := t.(KeyString); ok {
+			t = string(k)
+		}
+		out = append(out, t)
+	}
+}
+
+func TestTokenizerMatchesEncodingJSON(t *testing.T) {
+
+	for _, in := range tokenizerValid {
+		exp, err := referenceTokens(in)
+		if err != nil {
+			// encoding/json rejects numbers out of range; the Decoder does the same
+			_, derr := decoderTokens(strings.NewReader(in))
+			assert.Error(t, derr, in)
+			continue
+		}
+
+		out, err := decoderTokens(strings.NewReader(in))
+		require.NoError(t, err, in)
+		assert.Equal(t, exp, out, in)
+
+		// read one byte at a time to exercise refilling the buffer mid-token
+		out, err = decoderTokens(iotest.OneByteReader(strings.NewReader(in)))
+		require.NoError(t, err, in)
+		assert.Equal(t, exp, out, in)
+	}
+}
+
+func TestTokenizerInvalid(t *testing.T) {
+
+	for _, in := range tokenizerInvalid {
+		_, err := referenceTokens(in)
+		require.Error(t, err, "encoding/json accepts %s", in)
+
+		_, err = decoderTokens(strings.NewReader(in))
+		assert.Error(t, err, in)
+		_, err = decoderTokens(iotest.OneByteReader(strings.NewReader(in)))
+		assert.Error(t, err, in)
+	}
+}
+
+func TestTokenizerSyntaxError(t *testing.T) {
+
+	tests := []struct {
+		in     string
+		offset int64
+	}{
+		{in: `[1 2]`, offset: 3},
+		{in: `{"a" 1}`, offset: 5},
+		{in: `{"a":1 "b":2}`, offset: 7},
+		{in: `{1:2}`, offset: 1},
+		{in: `[x]`, offset: 1},
+		{in: `[1.x]`, offset: 3},
+		{in: `[-x]`, offset: 2},
+		{in: `[1ex]`, offset: 3},
+		{in: `["a\x"]`, offset: 3},
+		{in: `["\u12g4"]`, offset: 2},
+		{in: "[\"a\tb\"]", offset: 3},
+		{in: `[tx]`, offset: 2},
+		{in: `[nulx]`, offset: 4},
+		{in: "[\x80]", offset: 1},
+
+		// a closing delimiter where a value is expected
+		{in: `]`, offset: 0},
+		{in: ` }`, offset: 1},
+		{in: `[]]`, offset: 2},
+		{in: `1]2`, offset: 1},
+		{in: `0 }`, offset: 2},
+		{in: `-1}5e3 false`, offset: 2},
+		{in: `[}`, offset: 1},
+		{in: `{]`, offset: 1},
+		{in: `[1,]`, offset: 2},
+		{in: `[1,}`, offset: 2},
+		{in: `{"a":1,}`, offset: 6},
+		{in: `{"a":1,]`, offset: 6},
+		{in: `{"a":}`, offset: 5},
+		{in: `{"a":]`, offset: 5},
+		{in: `{ "" :]null }`, offset: 6},
+
+		// a delimiter out of place after a key or value
+		{in: `{"a"]`, offset: 4},
+		{in: `{ "" : }`, offset: 7},
+		{in: `[1}`, offset: 2},
+		{in: `{"a":1]`, offset: 6},
+		{in: `[1:2]`, offset: 2},
+		{in: `[1:]`, offset: 2},
+		{in: `{"a":1:2}`, offset: 6},
+		{in: `{"a":1:}`, offset: 6},
+
+		// an object key that is not a string
+		{in: `{t}`, offset: 2},
+		{in: `{true`, offset: 1},
+		{in: `{  null`, offset: 3},
+		{in: `{-x}`, offset: 2},
+		{in: `{[]:1}`, offset: 1},
+		{in: `{"a":1,{}:2}`, offset: 7},
+		{in: `[null ,{false]`, offset: 8},
+
+		// characters beyond ASCII are reported whole
+		{in: "[\"\xf0\x9f\x98\x80\"\x01]", offset: 7},
+		{in: "[\xf0\x9f\x98\x80]", offset: 1},
+		{in: "[\xe2\x82]", offset: 1},
+	}
+
+	for _, tst := range tests {
+		_, expected := referenceTokens(tst.in)
+		_, err := decoderTokens(strings.NewReader(tst.in))
+		if assert.IsType(t, &json.SyntaxError{}, err, tst.in) {
+			// the message is the one encoding/json reports for the same input
+			assert.EqualError(t, err, expected.Error(), tst.in)
+			assert.Equal(t, tst.offset, err.(*json.SyntaxError).Offset, tst.in)
+		}
+	}
+}
+
+func TestDecoderSyntaxError(t *testing.T) {
+
+	tests := []struct {
+		in     string
+		tokens int // the number of tokens read before the value is decoded
+		offset int64
+	}{
+		{in: `]`, offset: 0},
+		{in: `[1,]`, offset: 3},
+		{in: `[1,]`, tokens: 2, offset: 2},
+		{in: `[1 2]`, tokens: 2, offset: 3},
+		{in: `[1 :2]`, tokens: 2, offset: 3},
+		{in: `{t}`, offset: 1},
+		{in: `{t}`, tokens: 1, offset: 2},
+		{in: `{1:2}`, tokens: 1, offset: 1},
+		{in: `{[1]:2}`, tokens: 1, offset: 1},
+		{in: `{"a" 1}`, tokens: 2, offset: 5},
+		{in: `{"a":}`, offset: 5},
+		{in: `{"a":}`, tokens: 2, offset: 5},
+		{in: `{"a":]`, tokens: 2, offset: 5},
+		{in: `{"a":1,]`, tokens: 3, offset: 6},
+		{in: `{"a":1 :2}`, tokens: 3, offset: 7},
+
+		// a closing delimiter within the value is taken to end the object or array enclosing it
+		{in: `{"b":{"a":]}}`, tokens: 2, offset: 10},
+		{in: `{"b":[1,]}`, tokens: 2, offset: 8},
+		{in: `[1,[1,}]]`, tokens: 2, offset: 6},
+		{in: `[[1,}]]`, tokens: 1, offset: 4},
+	}
+
+	for _, tst := range tests {
+		ref := json.NewDecoder(strings.NewReader(tst.in))
+		d := NewDecoder(strings.NewReader(tst.in))
+		for i := 0; i < tst.tokens; i++ {
+			_, err := ref.Token()
+			require.NoError(t, err, tst.in)
+			_, err = d.Token()
+			require.NoError(t, err, tst.in)
+		}
+		var v interface{}
+		expected := ref.Decode(&v)
+		require.Error(t, expected, tst.in)
+		err := d.Decode(&v)
+		if assert.IsType(t, &json.SyntaxError{}, err, tst.in) {
+			assert.EqualError(t, err, expected.Error(), tst.in)
+			assert.Equal(t, tst.offset, err.(*json.SyntaxError).Offset, tst.in)
+		}
+	}
+}
+
+func TestTokenizerUnexpectedEOF(t *testing.T) {
+
+	for _, in := range []string{`["abc`, `[tr`, `[-`, `"abc`, `tru`, `nul`, `-`, `"a\u00`, `{"a":"`} {
+		_, err := decoderTokens(strings.NewReader(in))
+		assert.Equal(t, io.ErrUnexpectedEOF, err, in)
+	}
+
+	for _, in := range []string{`[1`, `{"a":`, `["abc`, `[tr`, `[-`, `"abc`, `tru`, `nul`, `-`, `"a\u00`} {
+		var v interface{}
+		assert.Equal(t, io.ErrUnexpectedEOF, NewDecoder(strings.NewReader(in)).Decode(&v), in)
+	}
+}
+
+func TestTokenizerEOFBetweenTokens(t *testing.T) {
+
+	for _, in := range []string{`[`, `{`, `[0`, `{"a"`, `{"a":`, `{"a":1,`, `[ [false ,"x"]`} {
+		// as with encoding/json, the tokens read are followed by io.EOF
+		exp, err := referenceTokens(in)
+		require.NoError(t, err, in)
+		out, err := decoderTokens(strings.NewReader(in))
+		require.NoError(t, err, in)
+		assert.Equal(t, exp, out, in)
+
+		ok, err := NewDecoder(strings.NewReader(in)).SeekTo(0, "missing")
+		assert.NoError(t, err, in)
+		assert.False(t, ok, in)
+	}
+}
+
+func TestDecoderUseNumber(t *testing.T) {
+
+	d := NewDecoder(strings.NewReader(`{"a":12345678901234567890,"b":[1.50]}`))
+	d.UseNumber()
+
+	tok, err := d.Token()
+	require.NoError(t, err)
+	assert.Equal(t, json.Delim('{'), tok)
+
+	tok, err = d.Token()
+	require.NoError(t, err)
+	assert.Equal(t, KeyString("a"), tok)
+
+	tok, err = d.Token()
+	require.NoError(t, err)
+	assert.Equal(t, json.Number("12345678901234567890"), tok)
+
+	ok, err := d.SeekTo("b")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	var v interface{}
+	require.NoError(t, d.Decode(&v))
+	assert.Equal(t, []interface{}{json.Number("1.50")}, v)
+}
+
+func TestDecoderDisallowUnknownFields(t *testing.T) {
+
+	d := NewDecoder(strings.NewReader(`[{"A":1},{"B":2}]`))
+	d.DisallowUnknownFields()
+
+	var v struct{ A int }
+	_, err := d.Token()
+	require.NoError(t, err)
+	assert.NoError(t, d.Decode(&v))
+	assert.Error(t, d.Decode(&v))
+}
+
+func TestDecoderMoreBufferedInputOffset(t *testing.T) {
+
+	d := NewDecoder(strings.NewReader(`[1, 2] rest`))
+
+	_, err := d.Token()
+	require.NoError(t, err)
+	assert.True(t, d.More())
+
+	var v int
+	require.NoError(t, d.Decode(&v))
+	require.NoError(t, d.Decode(&v))
+	assert.Equal(t, 2, v)
+	assert.False(t, d.More())
+
+	_, err = d.Token()
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), d.InputOffset())
+
+	rest, err := ioutil.ReadAll(d.Buffered())
+	require.NoError(t, err)
+	assert.Equal(t, " rest", string(rest))
+}
+
+func TestDecoderJSONDecoder(t *testing.T) {
+
+	d := NewDecoder(iotest.OneByteReader(strings.NewReader(`{"a":{"b":1}} {"a":2.50} [3]`)))
+	d.UseNumber()
+
+	ok, err := d.SeekTo("a", "b")
+	require.NoError(t, err)
+	require.True(t, ok)
+	var n json.Number
+	require.NoError(t, d.Decode(&n))
+	for i := 0; i < 2; i++ {
+		_, err = d.Token()
+		require.NoError(t, err)
+	}
+
+	// the json.Decoder continues with the next value, and numbers are still decoded as json.Number
+	jd := d.JSONDecoder()
+	var v interface{}
+	require.NoError(t, jd.Decode(&v))
+	assert.Equal(t, map[string]interface{}{"a": json.Number("2.50")}, v)
+	require.NoError(t, jd.Decode(&v))
+	assert.Equal(t, []interface{}{json.Number("3")}, v)
+	assert.Equal(t, io.EOF, jd.Decode(&v))
+}
+
+func TestDecoderDecodeStream(t *testing.T) {
+
+	d := NewDecoder(iotest.OneByteReader(bytes.NewBufferString(`{"a":"x"} [1,2] "s" 4`)))
+
+	var out []interface{}
+	for {
+		var v interface{}
+		err := d.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		out = append(out, v)
+	}
+	assert.Equal(t, []interface{}{map[string]interface{}{"a": "x"}, []interface{}{float64(1), float64(2)}, "s", float64(4)}, out)
+}
+
+func TestDecoderDecodeNotAtValue(t *testing.T) {
+
+	d := NewDecoder(strings.NewReader(`{"a":1}`))
+	_, err := d.Token()
+	require.NoError(t, err)
+
+	var v interface{}
+	assert.IsType(t, &json.SyntaxError{}, d.Decode(&v))
+
+	for _, in := range []string{`[1 2]`, `{"a" 1}`} {
+		d = NewDecoder(strings.NewReader(in))
+		_, err = d.Token()
+		require.NoError(t, err)
+		_, err = d.Token()
+		require.NoError(t, err)
+		assert.IsType(t, &json.SyntaxError{}, d.Decode(&v), in)
+	}
+}
+
+func TestScanSkipsUnmatchedValues(t *testing.T) {
+
+	// the skipped values contain strings that would exceed the buffer if they were retained
+	big := strings.Repeat("x", 3*minRead)
+	j := `{"skip":{"a":["` + big + `",{"b":"` + big + `"}]},"keep":{"v":1},"after":"` + big + `"}`
+
+	var v int
+	actions := &PathActions{}
+	actions.Add(func(d *Decoder) error {
+		return d.Decode(&v)
+	}, "keep", "v")
+
+	d := NewDecoder(strings.NewReader(j))
+	_, err := d.Scan(actions)
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+	assert.True(t, cap(d.tok.buf) < 2*len(big), "buffer grew to %v", cap(d.tok.buf))
+}
@@ -0,0 +1,228 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// TransformFunc computes the replacement for a value matched by Transformer.Map. The value is passed as it appears
+// in the input, and the result is marshalled with encoding/json.
+type TransformFunc func(path JsonPath, value json.RawMessage) (interface{}, error)
+
+// Transformer copies a JSON stream from a Decoder to an Encoder, rewriting the values at certain paths as they pass
+// through. Rules are registered with paths in the same way as PathActions, and AnyIndex may be used in a path to
+// match any array index.
+//
+// Values that are not affected by any rule are copied as they appear in the input, so untouched numbers and strings
+// keep their original formatting. A Transformer can be created once and used many times, including concurrently.
+type Transformer struct {
+	paths PathActions
+	rules map[*pathNode]*transformRule // the rules for each trie node of paths
+}
+
+// transformRule holds the rules registered for a single path.
+type transformRule struct {
+	delete  bool
+	rename  *string
+	replace TransformFunc
+}
+
+var (
+	literalTrue  = []byte("true")
+	literalFalse = []byte("false")
+	literalNull  = []byte("null")
+)
+
+// Delete removes the value at path. When the value belongs to an object its key is removed as well. Delete takes
+// precedence over the other rules for the same path.
+func (t *Transformer) Delete(path ...interface{}) {
+	t.update(path, func(r *transformRule) { r.delete = true })
+}
+
+// Rename changes the object key at path to key. The value is copied subject to the other rules. Rename has no effect
+// on array elements.
+func (t *Transformer) Rename(key string, path ...interface{}) {
+	t.update(path, func(r *transformRule) { r.rename = &key })
+}
+
+// Replace replaces the value at path with v, marshalled with encoding/json. It overrides any earlier Replace or Map
+// for the same path.
+func (t *Transformer) Replace(v interface{}, path ...interface{}) {
+	t.update(path, func(r *transformRule) {
+		r.replace = func(JsonPath, json.RawMessage) (interface{}, error) { return v, nil }
+	})
+}
+
+// Map replaces the value at path with the result of calling f. It overrides any earlier Replace or Map for the same
+// path.
+func (t *Transformer) Map(f TransformFunc, path ...interface{}) {
+	t.update(path, func(r *transformRule) { r.replace = f })
+}
+
+func (t *Transformer) update(path []interface{}, f func(r *transformRule)) {
+	node := t.paths.insert(path)
+	if t.rules == nil {
+		t.rules = map[*pathNode]*transformRule{}
+	}
+	r := t.rules[node]
+	if r == nil {
+		r = &transformRule{}
+		t.rules[node] = r
+	}
+	f(r)
+}
+
+// ruleAt returns the rule for the compiled node n, or nil if there is none. Where n matches several paths, the rule
+// of the preferred path is used.
+func (t *Transformer) ruleAt(n *matchNode) *transformRule {
+	if n == nil {
+		return nil
+	}
+	for _, src := range n.sources {
+		if r := t.rules[src]; r != nil {
+			return r
+		}
+	}
+	return nil
+}
+
+// Transform copies the remainder of the JSON stream from d to e, applying the rules to each value. Paths are
+// matched from the root of the stream, so Transform is normally called before any tokens have been read from d.
+// Transform returns nil when the end of the stream is reached.
+func (t *Transformer) Transform(d *Decoder, e *Encoder) error {
+
+	state := matchState{m: t.paths.Compile()}
+	for {
+		var node *matchNode
+		switch d.context {
+		case objKey:
+			if !d.More() {
+				if err := copyEnd(d, e); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := d.next(); err != nil {
+				return err
+			}
+			node = state.reset(d.path)
+			r := t.ruleAt(node)
+			if r != nil && r.delete {
+				if _, err := d.skip(false); err != nil {
+					return err
+				}
+				continue
+			}
+			var err error
+			if r != nil && r.rename != nil {
+				err = e.WriteKey(*r.rename)
+			} else {
+				err = e.writeKey(string(d.path.top().key), d.tok.raw)
+			}
+			if err != nil {
+				return err
+			}
+
+		case arrValue:
+			if !d.More() {
+				if err := copyEnd(d, e); err != nil {
+					return err
+				}
+				continue
+			}
+			// match the path of the next element, which the Decoder has not yet counted
+			d.path.incTop()
+			node = state.reset(d.path)
+			d.path.top().index--
+			if r := t.ruleAt(node); r != nil && r.delete {
+				if _, err := d.skip(false); err != nil {
+					return err
+				}
+				continue
+			}
+
+		default:
+			if !d.More() {
+				// the end of the stream, or an error
+				_, err := d.next()
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+			node = state.reset(d.path)
+			if r := t.ruleAt(node); r != nil && r.delete {
+				if _, err := d.skip(false); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if err := t.value(d, e, node); err != nil {
+			return err
+		}
+	}
+}
+
+// value copies the beginning of the next value from d to e. The value is copied completely unless node has rules
+// for the paths within it, in which case only its first token is copied.
+func (t *Transformer) value(d *Decoder, e *Encoder, node *matchNode) error {
+
+	if r := t.ruleAt(node); r != nil && r.replace != nil {
+		raw, err := d.skip(true)
+		if err != nil {
+			return err
+		}
+		v, err := r.replace(d.Path(), append(json.RawMessage(nil), raw...))
+		if err != nil {
+			return err
+		}
+		return e.WriteValue(v)
+	}
+
+	if node == nil || node.leaf() {
+		// no rules apply within the value, so copy it as it is
+		raw, err := d.skip(true)
+		if err != nil {
+			return err
+		}
+		return e.writeRaw(raw)
+	}
+
+	return copyToken(d, e)
+}
+
+// copyToken copies the next token, which must begin a value, from d to e. A scalar is copied as it appears in the
+// input.
+func copyToken(d *Decoder, e *Encoder) error {
+	kind, err := d.next()
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case tokenObjectStart:
+		return e.WriteToken(json.Delim('{'))
+	case tokenArrayStart:
+		return e.WriteToken(json.Delim('['))
+	case tokenTrue:
+		return e.writeRaw(literalTrue)
+	case tokenFalse:
+		return e.writeRaw(literalFalse)
+	case tokenNull:
+		return e.writeRaw(literalNull)
+	}
+	return e.writeRaw(d.tok.raw)
+}
+
+// copyEnd copies the end of the current object or array from d to e.
+func copyEnd(d *Decoder, e *Encoder) error {
+	kind, err := d.next()
+	if err != nil {
+		return err
+	}
+	if kind == tokenObjectEnd {
+		return e.WriteToken(json.Delim('}'))
+	}
+	return e.WriteToken(json.Delim(']'))
+}
@@ -0,0 +1,111 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func transform(t *testing.T, tr *Transformer, in string) string {
+	var out bytes.Buffer
+	require.NoError(t, tr.Transform(NewDecoder(strings.NewReader(in)), NewEncoder(&out)))
+	return out.String()
+}
+
+func TestTransformerCopiesUntouchedBytes(t *testing.T) {
+
+	in := `{"a": 1.50e+2, "b" : ["é", {"c": [ 1 ,2 ]}], "d": {"e": true}}`
+
+	tr := &Transformer{}
+	assert.Equal(t, in+"\n", transform(t, tr, in))
+
+	// values are copied as they are, but the structure around a rule is re-encoded
+	tr.Delete("d", "x")
+	assert.Equal(t, `{"a":1.50e+2,"b":["é", {"c": [ 1 ,2 ]}],"d":{"e":true}}`+"\n", transform(t, tr, in))
+}
+
+func TestTransformerRules(t *testing.T) {
+
+	tr := &Transformer{}
+	tr.Delete("password")
+	tr.Delete("users", AnyIndex, "internal")
+	tr.Rename("userName", "users", AnyIndex, "user_name")
+	tr.Replace("***", "users", AnyIndex, "token")
+	tr.Rename("total", "count")
+	tr.Map(func(path JsonPath, v json.RawMessage) (interface{}, error) {
+		var n int
+		err := json.Unmarshal(v, &n)
+		return n * 2, err
+	}, "count")
+
+	in := `{"password":"x","users":[{"user_name":"ann","token":"t1","internal":{"a":1}},{"token":{"t":2},"user_name":"bob"}],"count":2}`
+	exp := `{"users":[{"userName":"ann","token":"***"},{"token":"***","userName":"bob"}],"total":4}` + "\n"
+	assert.Equal(t, exp, transform(t, tr, in))
+}
+
+func TestTransformerArrays(t *testing.T) {
+
+	tr := &Transformer{}
+	tr.Delete("a", 1)
+	tr.Replace(nil, "a", 3)
+	tr.Delete("b", AnyIndex)
+
+	var paths []JsonPath
+	tr.Map(func(path JsonPath, v json.RawMessage) (interface{}, error) {
+		paths = append(paths, path)
+		return string(v), nil
+	}, "c", AnyIndex, AnyIndex)
+
+	in := `{"a":[0,1,2,3,4],"b":[1,2],"c":[[1,2],[3]]}`
+	exp := `{"a":[0,2,null,4],"b":[],"c":[["1","2"],["3"]]}` + "\n"
+	assert.Equal(t, exp, transform(t, tr, in))
+	assert.Equal(t, []JsonPath{{"c", 0, 0}, {"c", 0, 1}, {"c", 1, 0}}, paths)
+}
+
+func TestTransformerStream(t *testing.T) {
+
+	tr := &Transformer{}
+	tr.Delete("b")
+	tr.Replace(0, "a")
+
+	assert.Equal(t, "{\"a\":0}\n[1]\n{\"a\":0}\n", transform(t, tr, `{"a":1,"b":2} [1] {"b":3,"a":4}`))
+}
+
+func TestTransformerRoot(t *testing.T) {
+
+	tr := &Transformer{}
+	tr.Replace("x")
+	assert.Equal(t, "\"x\"\n\"x\"\n", transform(t, tr, `{"a":1} [2]`))
+}
+
+func TestTransformerIndent(t *testing.T) {
+
+	tr := &Transformer{}
+	tr.Delete("b")
+
+	var out bytes.Buffer
+	e := NewEncoder(&out)
+	e.SetIndent("", "  ")
+	require.NoError(t, tr.Transform(NewDecoder(strings.NewReader(`{"a":{"x":[1,2]},"b":0}`)), e))
+	assert.Equal(t, "{\n  \"a\": {\n    \"x\": [\n      1,\n      2\n    ]\n  }\n}\n", out.String())
+}
+
+func TestTransformerErrors(t *testing.T) {
+
+	tr := &Transformer{}
+	tr.Map(func(path JsonPath, v json.RawMessage) (interface{}, error) {
+		return nil, errors.New("failed")
+	}, "a")
+
+	var out bytes.Buffer
+	err := tr.Transform(NewDecoder(strings.NewReader(`{"a":1}`)), NewEncoder(&out))
+	assert.EqualError(t, err, "failed")
+
+	err = (&Transformer{}).Transform(NewDecoder(strings.NewReader(`{"a":[1,}`)), NewEncoder(&out))
+	assert.IsType(t, &json.SyntaxError{}, err)
+}
@@ -0,0 +1,253 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// MissingFieldError is returned by Unmarshal when the input has no value for one or more required fields.
+type MissingFieldError struct {
+	Fields []string // names of the missing fields, in the order they are declared
+	Paths  []string // path expressions of the missing fields, from their tags
+}
+
+func (e *MissingFieldError) Error() string {
+	missing := make([]string, len(e.Fields))
+	for i := range e.Fields {
+		missing[i] = fmt.Sprintf("%s (%s)", e.Fields[i], e.Paths[i])
+	}
+	return "jsonpath: missing required fields " + strings.Join(missing, ", ")
+}
+
+// pathField receives the value at a path: a struct field with a jsonpath tag, or a result of Extract.
+type pathField struct {
+	name     string
+	expr     string
+	path     JsonPath
+	value    reflect.Value
+	required bool
+	appends  bool // the path contains AnyIndex and the field is a slice, so each match is appended to it
+	set      bool
+}
+
+// Unmarshal reads a JSON value from r and stores values from it in the struct pointed to by v. Each field with a
+// jsonpath tag receives the value at the path given by the tag, as parsed by ParsePath, for example
+//
+//	type Order struct {
+//		ID       string   `jsonpath:"$.order.id,required"`
+//		City     string   `jsonpath:"$.order.shipping.address.city"`
+//		FirstSKU string   `jsonpath:"$.order.lines[0].sku"`
+//		SKUs     []string `jsonpath:"$.order.lines[*].sku"`
+//	}
+//
+// The fields are filled in a single pass over the input with a PathActions built from the tags. Values are
+// unmarshalled into the fields with encoding/json. When the path of a slice field contains AnyIndex, each value
+// that matches is appended to the slice. Fields without a jsonpath tag, or with the tag "-", are ignored, and the
+// fields of embedded structs are included.
+//
+// A field whose tag has the required option, as in `jsonpath:"$.id,required"`, must be present in the input. If any
+// required fields are missing Unmarshal returns a *MissingFieldError after filling the other fields.
+func Unmarshal(r io.Reader, v interface{}) error {
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("jsonpath: Unmarshal requires a non-nil pointer to a struct, not %T", v)
+	}
+	fields, err := pathFields(rv.Elem(), nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fill(NewDecoder(r), fields, nil); err != nil {
+		return err
+	}
+
+	missing := &MissingFieldError{}
+	for _, f := range fields {
+		if f.required && !f.set {
+			missing.Fields = append(missing.Fields, f.name)
+			missing.Paths = append(missing.Paths, f.expr)
+		}
+	}
+	if len(missing.Fields) > 0 {
+		return missing
+	}
+	return nil
+}
+
+// fill fills fields from the next value read from d. done is passed to fieldActions.
+func fill(d *Decoder, fields []*pathField, done func() bool) error {
+	for _, f := range fields {
+		if len(f.path) == 0 {
+			// a field receives the whole value
+			return fieldAction(fields, 0, done)(d)
+		}
+	}
+	_, err := d.Scan(fieldActions(fields, 0, done))
+	return err
+}
+
+// pathFields returns the fields of the struct v that have jsonpath tags, including those of embedded structs.
+func pathFields(v reflect.Value, fields []*pathField) ([]*pathField, error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("jsonpath")
+		if !ok && sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			var err error
+			if fields, err = pathFields(v.Field(i), fields); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !ok || tag == "-" || sf.PkgPath != "" {
+			continue
+		}
+
+		opts := strings.Split(tag, ",")
+		path, err := ParsePath(opts[0])
+		if err != nil {
+			return nil, fmt.Errorf("jsonpath: field %s: %v", sf.Name, err)
+		}
+		f := &pathField{name: sf.Name, expr: opts[0], path: path, value: v.Field(i)}
+		for _, o := range opts[1:] {
+			switch o {
+			case "required":
+				f.required = true
+			default:
+				return nil, fmt.Errorf("jsonpath: field %s: unknown tag option %q", sf.Name, o)
+			}
+		}
+		if f.value.Kind() == reflect.Slice {
+			for _, e := range path {
+				if e == AnyIndex {
+					f.appends = true
+				}
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// fieldActions returns the PathActions that fill fields, whose paths are relative to the given depth. If done is not
+// nil, the actions stop the scan by returning errScanDone once it reports true.
+//
+// Fields whose values lie within the value of another field are filled from that field's value.
+func fieldActions(fields []*pathField, depth int, done func() bool) *PathActions {
+	patterns := make([]JsonPath, len(fields))
+	for i, f := range fields {
+		patterns[i] = f.path[depth:]
+	}
+
+	action := fieldAction(fields, depth, done)
+	actions := &PathActions{}
+	for _, p := range patterns {
+		if len(p) == 0 || hasPatternAbove(patterns, p) {
+			continue
+		}
+		actions.Add(action, p...)
+	}
+	return actions
+}
+
+// isIndex reports whether the path element e is an array index.
+func isIndex(e interface{}) bool {
+	_, ok := e.(int)
+	return ok
+}
+
+// matchesPrefix reports whether the position s matches the start of the path pattern p.
+func matchesPrefix(s pathStack, p JsonPath) bool {
+	for i := range s {
+		if !s[i].matches(p[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasPatternAbove reports whether one of patterns is a proper prefix of p that matches every position p matches,
+// so that the value at p lies within its value.
+func hasPatternAbove(patterns []JsonPath, p JsonPath) bool {
+	for _, o := range patterns {
+		if len(o) > 0 && len(o) < len(p) && covers(o, p[:len(o)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// covers reports whether the pattern o matches every position matched by the pattern p of the same length.
+func covers(o, p JsonPath) bool {
+	for i := range o {
+		if o[i] != p[i] && !(o[i] == AnyIndex && isIndex(p[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldAction returns the action that fills the fields, whose paths are relative to the given depth, from the value
+// at the current position. Fields at the position are filled with the value and fields within it are filled by
+// scanning it.
+func fieldAction(fields []*pathField, depth int, done func() bool) DecodeAction {
+	return func(d *Decoder) error {
+		// an action for an array element is called before the element is counted
+		if d.context == arrValue {
+			d.path.incTop()
+		}
+		var at, below []*pathField
+		for _, f := range fields {
+			if rel := f.path[depth:]; len(rel) >= len(d.path) && matchesPrefix(d.path, rel) {
+				if len(rel) == len(d.path) {
+					at = append(at, f)
+				} else {
+					below = append(below, f)
+				}
+			}
+		}
+		n := len(d.path)
+		if d.context == arrValue {
+			d.path.top().index--
+		}
+
+		raw, err := d.skip(true)
+		if err != nil {
+			return err
+		}
+		for _, f := range at {
+			if err := f.store(raw); err != nil {
+				return err
+			}
+		}
+		if len(below) > 0 {
+			if _, err := NewDecoder(bytes.NewReader(raw)).Scan(fieldActions(below, depth+n, done)); err != nil {
+				return err
+			}
+		}
+		if done != nil && done() {
+			return errScanDone
+		}
+		return nil
+	}
+}
+
+// store unmarshals raw into the field.
+func (f *pathField) store(raw []byte) error {
+	if f.appends {
+		elem := reflect.New(f.value.Type().Elem())
+		if err := json.Unmarshal(raw, elem.Interface()); err != nil {
+			return fmt.Errorf("jsonpath: field %s: %v", f.name, err)
+		}
+		f.value.Set(reflect.Append(f.value, elem.Elem()))
+	} else if err := json.Unmarshal(raw, f.value.Addr().Interface()); err != nil {
+		return fmt.Errorf("jsonpath: field %s: %v", f.name, err)
+	}
+	f.set = true
+	return nil
+}
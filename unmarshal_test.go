@@ -0,0 +1,100 @@
+package jsonpath
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const unmarshalDoc = `{
+	"order": {
+		"id": "A-1",
+		"total": 12.5,
+		"shipping": {"address": {"city": "Oslo", "zip": "0150"}},
+		"lines": [
+			{"sku": "x1", "qty": 1},
+			{"sku": "x2", "qty": 3},
+			{"sku": "x3", "qty": 2}
+		]
+	}
+}`
+
+type orderBase struct {
+	ID string `jsonpath:"$.order.id,required"`
+}
+
+type order struct {
+	orderBase
+	Total    float64           `jsonpath:"$.order.total"`
+	City     string            `jsonpath:"$.order.shipping.address.city"`
+	Address  map[string]string `jsonpath:"$.order.shipping.address"`
+	FirstSKU string            `jsonpath:"$.order.lines[0].sku"`
+	LastQty  int               `jsonpath:"order.lines[2].qty"`
+	SKUs     []string          `jsonpath:"$.order.lines[*].sku"`
+	Ignored  string            `jsonpath:"-"`
+	Untagged string
+}
+
+func TestUnmarshal(t *testing.T) {
+
+	var o order
+	require.NoError(t, Unmarshal(bytes.NewBufferString(unmarshalDoc), &o))
+	assert.Equal(t, "A-1", o.ID)
+	assert.Equal(t, 12.5, o.Total)
+	assert.Equal(t, "Oslo", o.City)
+	assert.Equal(t, map[string]string{"city": "Oslo", "zip": "0150"}, o.Address)
+	assert.Equal(t, "x1", o.FirstSKU)
+	assert.Equal(t, 2, o.LastQty)
+	assert.Equal(t, []string{"x1", "x2", "x3"}, o.SKUs)
+	assert.Empty(t, o.Ignored)
+	assert.Empty(t, o.Untagged)
+}
+
+func TestUnmarshalRoot(t *testing.T) {
+
+	var v struct {
+		All map[string]interface{} `jsonpath:"$"`
+		A   int                    `jsonpath:"$.a"`
+	}
+	require.NoError(t, Unmarshal(bytes.NewBufferString(`{"a":1}`), &v))
+	assert.Equal(t, map[string]interface{}{"a": 1.0}, v.All)
+	assert.Equal(t, 1, v.A)
+}
+
+func TestUnmarshalMissing(t *testing.T) {
+
+	var v struct {
+		A int    `jsonpath:"$.a,required"`
+		B string `jsonpath:"$.b"`
+		C []int  `jsonpath:"$.c[*],required"`
+	}
+	err := Unmarshal(bytes.NewBufferString(`{"b":"x","c":[]}`), &v)
+	var merr *MissingFieldError
+	require.True(t, errors.As(err, &merr), "%v", err)
+	assert.Equal(t, []string{"A", "C"}, merr.Fields)
+	assert.Equal(t, []string{"$.a", "$.c[*]"}, merr.Paths)
+	assert.Equal(t, "x", v.B)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+
+	var s struct {
+		A int `jsonpath:"$.a"`
+	}
+	assert.Error(t, Unmarshal(bytes.NewBufferString(`{}`), s))
+	assert.Error(t, Unmarshal(bytes.NewBufferString(`{}`), (*order)(nil)))
+	assert.Error(t, Unmarshal(bytes.NewBufferString(`{"a":"x"}`), &s))
+	assert.Error(t, Unmarshal(bytes.NewBufferString(`{"a":`), &s))
+
+	var bad struct {
+		A int `jsonpath:"$..a"`
+	}
+	assert.Error(t, Unmarshal(bytes.NewBufferString(`{}`), &bad))
+	var opt struct {
+		A int `jsonpath:"$.a,omitempty"`
+	}
+	assert.Error(t, Unmarshal(bytes.NewBufferString(`{}`), &opt))
+}